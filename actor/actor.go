@@ -0,0 +1,464 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrStopped actor已经停止
+	ErrStopped = errors.New("actor: stopped")
+	// ErrNameTaken 名字已经被其他actor占用
+	ErrNameTaken = errors.New("actor: name taken")
+	// ErrNoReply Ask的消息处理完了，但是actor没有调用Respond
+	ErrNoReply = errors.New("actor: no reply")
+)
+
+// Receiver actor的业务逻辑。消息是一条一条顺序处理的，所以Receiver里的状态不需要加锁。
+// 返回错误或者panic时，由父actor的监管策略决定怎么处理
+type Receiver[M any] interface {
+	Receive(c *Context[M], msg M) error
+}
+
+// PreStarter 可选的生命周期钩子，actor启动和每次重启之后、处理第一条消息之前调用，返回错误同样交给监管策略处理
+type PreStarter[M any] interface {
+	PreStart(c *Context[M]) error
+}
+
+// PostStopper 可选的生命周期钩子，actor停止和每次重启之前调用，此时子actor都已经停止了
+type PostStopper interface {
+	PostStop()
+}
+
+// Option Spawn的可选项
+type Option func(*options)
+
+type options struct {
+	name     string
+	size     int
+	strategy Strategy
+}
+
+// Name 给actor起名字，有名字的actor会注册到System中，可以用Lookup查找
+func Name(name string) Option {
+	return func(o *options) { o.name = name }
+}
+
+// Bounded 有界邮箱，满了之后Tell会阻塞
+func Bounded(size int) Option {
+	return func(o *options) { o.size = size }
+}
+
+// Unbounded 无界邮箱，默认就是无界的
+func Unbounded() Option {
+	return func(o *options) { o.size = 0 }
+}
+
+// Supervise 指定这个actor监管子actor的策略
+func Supervise(s Strategy) Option {
+	return func(o *options) { o.strategy = s }
+}
+
+// Ref actor的引用，只能通过Ref给actor发消息，拿不到actor内部的状态
+type Ref[M any] struct {
+	c *cell[M]
+}
+
+// Name actor的名字
+func (r *Ref[M]) Name() string { return r.c.name }
+
+// Tell 发送消息，不等待处理结果
+func (r *Ref[M]) Tell(ctx context.Context, msg M) error {
+	return r.c.put(ctx, envelope[M]{msg: msg})
+}
+
+// Stop 停止actor，正在处理的消息处理完后停止，邮箱中剩余的消息被丢弃。可以通过Done等待停止完成
+func (r *Ref[M]) Stop() { r.c.stop() }
+
+// Done actor完全停止后关闭
+func (r *Ref[M]) Done() <-chan struct{} { return r.c.done }
+
+// Ask 发送消息并等待actor通过Context.Respond回复，ctx结束时返回ctx的错误
+func Ask[R, M any](ctx context.Context, r *Ref[M], msg M) (R, error) {
+	var zero R
+	reply := make(chan askReply, 1)
+	if err := r.c.put(ctx, envelope[M]{msg: msg, reply: reply}); err != nil {
+		return zero, err
+	}
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case rep := <-reply:
+		if rep.err != nil {
+			return zero, rep.err
+		}
+		v, ok := rep.value.(R)
+		if !ok {
+			return zero, fmt.Errorf("actor: reply type %T is not %T", rep.value, zero)
+		}
+		return v, nil
+	}
+}
+
+// Context 处理消息时的上下文，本身也是一个context.Context，actor停止时被取消
+type Context[M any] struct {
+	context.Context
+	c     *cell[M]
+	reply chan askReply
+}
+
+// Self 自己的引用
+func (x *Context[M]) Self() *Ref[M] { return x.c.ref }
+
+// System 所属的System
+func (x *Context[M]) System() *System { return x.c.sys }
+
+// Respond 回复Ask的调用方，Tell发来的消息没有调用方，回复会被忽略。只有第一次回复有效
+func (x *Context[M]) Respond(v any) {
+	if x.reply == nil {
+		return
+	}
+	x.reply <- askReply{value: v}
+	x.reply = nil
+}
+
+// Spawn 在System下创建一个顶层actor，由System按照它的策略监管
+func Spawn[M any](sys *System, factory func() Receiver[M], opts ...Option) (*Ref[M], error) {
+	return spawn(sys, &sys.root, factory, opts)
+}
+
+// SpawnChild 创建子actor，由parent按照它的策略监管。parent停止时子actor也会先停止
+func SpawnChild[M, P any](parent *Context[P], factory func() Receiver[M], opts ...Option) (*Ref[M], error) {
+	return spawn(parent.c.sys, &parent.c.node, factory, opts)
+}
+
+// cell actor的运行时，持有邮箱、当前的Receiver实例和在监管树中的位置
+type cell[M any] struct {
+	node
+	sys      *System
+	factory  func() Receiver[M]
+	box      *mailbox[M]
+	ref      *Ref[M]
+	instance Receiver[M]
+	restarts int
+}
+
+func spawn[M any](sys *System, parent *node, factory func() Receiver[M], opts []Option) (*Ref[M], error) {
+	o := options{strategy: DefaultStrategy}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &cell[M]{
+		node: node{
+			name:        o.name,
+			parent:      parent,
+			strategy:    o.strategy,
+			children:    make(map[*node]struct{}),
+			escalations: make(chan error, 1),
+			stopCh:      make(chan struct{}),
+			done:        make(chan struct{}),
+			ctx:         ctx,
+			cancel:      cancel,
+		},
+		sys:     sys,
+		factory: factory,
+		box:     newMailbox[M](o.size),
+	}
+	c.ref = &Ref[M]{c: c}
+	if o.name != "" {
+		if err := sys.register(o.name, c.ref); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	if !parent.adopt(&c.node) {
+		sys.unregister(o.name)
+		cancel()
+		return nil, ErrStopped
+	}
+	go c.run()
+	return c.ref, nil
+}
+
+func (c *cell[M]) put(ctx context.Context, env envelope[M]) error {
+	select {
+	case <-c.stopCh:
+		return ErrStopped
+	default:
+	}
+	if err := c.box.put(ctx, c.stopCh, env); err != nil {
+		return err
+	}
+	// 放入的同时actor停止了，这条消息不会再被处理
+	select {
+	case <-c.done:
+		c.rejectPending()
+	default:
+	}
+	return nil
+}
+
+func (c *cell[M]) run() {
+	defer c.finish()
+	if !c.start() {
+		return
+	}
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case err := <-c.escalations:
+			// 子actor把处理不了的错误上报了，当作自己的失败处理
+			if !c.fail(err) {
+				return
+			}
+		case <-c.box.signal:
+			for {
+				select {
+				case <-c.stopCh:
+					return
+				default:
+				}
+				env, ok := c.box.pop()
+				if !ok {
+					break
+				}
+				if !c.process(env) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// start 创建新的实例并调用PreStart，返回false表示actor应该停止。
+// PreStart失败时交给fail处理，需要重启的话fail会再调用start，这里不能再重试
+func (c *cell[M]) start() bool {
+	c.instance = c.factory()
+	if ps, ok := c.instance.(PreStarter[M]); ok {
+		if err := ps.PreStart(&Context[M]{Context: c.ctx, c: c}); err != nil {
+			return c.fail(fmt.Errorf("actor %s: pre-start: %w", c.name, err))
+		}
+	}
+	return true
+}
+
+func (c *cell[M]) process(env envelope[M]) bool {
+	x := &Context[M]{Context: c.ctx, c: c, reply: env.reply}
+	err := c.receive(x, env.msg)
+	if x.reply != nil {
+		if err != nil {
+			x.reply <- askReply{err: err}
+		} else {
+			x.reply <- askReply{err: ErrNoReply}
+		}
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	return true
+}
+
+func (c *cell[M]) receive(x *Context[M], msg M) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("actor %s: panic: %v", c.name, r)
+		}
+	}()
+	return c.instance.Receive(x, msg)
+}
+
+// fail 按照父actor的监管策略处理错误，返回false表示actor应该停止
+func (c *cell[M]) fail(err error) bool {
+	directive := c.parent.strategy.decide(err)
+	if directive == Restart && c.restarts >= c.parent.strategy.maxRestarts() {
+		directive = Stop
+	}
+	switch directive {
+	case Resume:
+		return true
+	case Restart:
+		c.restarts++
+		c.stopChildren()
+		c.postStop()
+		return c.start()
+	case Escalate:
+		c.parent.escalate(fmt.Errorf("child %s: %w", c.name, err))
+	}
+	return false
+}
+
+func (c *cell[M]) postStop() {
+	if ps, ok := c.instance.(PostStopper); ok {
+		ps.PostStop()
+	}
+}
+
+func (c *cell[M]) finish() {
+	c.stop()
+	c.cancel()
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.stopChildren()
+	if c.instance != nil {
+		c.postStop()
+	}
+	c.sys.unregister(c.name)
+	c.parent.orphan(&c.node)
+	close(c.done)
+	c.rejectPending()
+}
+
+// rejectPending 停止后邮箱中剩余的Ask请求直接返回ErrStopped，避免调用方一直等到超时
+func (c *cell[M]) rejectPending() {
+	for _, env := range c.box.drain() {
+		if env.reply != nil {
+			env.reply <- askReply{err: ErrStopped}
+		}
+	}
+}
+
+// node 监管树中的节点，和消息类型无关，这样不同消息类型的actor可以互相监管
+type node struct {
+	name        string
+	parent      *node
+	strategy    Strategy
+	escalations chan error
+
+	mu       sync.Mutex
+	children map[*node]struct{}
+	closed   bool
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	done     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func (n *node) stop() {
+	n.stopOnce.Do(func() { close(n.stopCh) })
+}
+
+func (n *node) adopt(child *node) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return false
+	}
+	n.children[child] = struct{}{}
+	return true
+}
+
+func (n *node) orphan(child *node) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.children, child)
+}
+
+// stopChildren 停止所有子actor并等待它们退出
+func (n *node) stopChildren() {
+	n.mu.Lock()
+	children := make([]*node, 0, len(n.children))
+	for child := range n.children {
+		children = append(children, child)
+	}
+	n.mu.Unlock()
+	for _, child := range children {
+		child.stop()
+	}
+	for _, child := range children {
+		<-child.done
+	}
+}
+
+// escalate 把错误上报给这个节点，由它的父节点决定怎么处理。根节点没有父节点，上报的错误被丢弃，失败的actor已经停止了
+func (n *node) escalate(err error) {
+	if n.escalations == nil {
+		return
+	}
+	select {
+	case n.escalations <- err:
+	case <-n.stopCh:
+	default:
+		// 父节点可能正在重启并等待这个子节点退出，不能在这里阻塞
+		go func() {
+			select {
+			case n.escalations <- err:
+			case <-n.stopCh:
+			}
+		}()
+	}
+}
+
+// System actor的运行环境，是所有顶层actor的父节点，同时维护名字到actor的注册表
+type System struct {
+	root node
+
+	mu       sync.Mutex
+	registry map[string]any
+}
+
+// NewSystem 创建System，strategy是顶层actor的监管策略
+func NewSystem(strategy Strategy) *System {
+	return &System{
+		root: node{
+			name:     "/",
+			strategy: strategy,
+			children: make(map[*node]struct{}),
+			stopCh:   make(chan struct{}),
+			done:     make(chan struct{}),
+		},
+		registry: make(map[string]any),
+	}
+}
+
+// Lookup 按名字查找actor，名字不存在或者消息类型不是M时返回false
+func Lookup[M any](sys *System, name string) (*Ref[M], bool) {
+	sys.mu.Lock()
+	defer sys.mu.Unlock()
+	ref, ok := sys.registry[name].(*Ref[M])
+	return ref, ok
+}
+
+// Shutdown 停止所有actor，等待它们退出或者ctx结束
+func (s *System) Shutdown(ctx context.Context) error {
+	s.root.mu.Lock()
+	s.root.closed = true
+	s.root.mu.Unlock()
+	s.root.stop()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.root.stopChildren()
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *System) register(name string, ref any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.registry[name]; ok {
+		return fmt.Errorf("%w: %s", ErrNameTaken, name)
+	}
+	s.registry[name] = ref
+	return nil
+}
+
+func (s *System) unregister(name string) {
+	if name == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.registry, name)
+}
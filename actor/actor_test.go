@@ -0,0 +1,201 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counterMsg 计数器actor的消息
+type counterMsg struct {
+	add   int
+	get   bool
+	crash bool
+}
+
+// counter 原本需要一个带锁的结构体，现在状态只在actor自己的goroutine里被访问，不需要锁
+type counter struct {
+	n      int
+	events chan<- string
+}
+
+func (c *counter) PreStart(ctx *Context[counterMsg]) error {
+	c.events <- "start"
+	return nil
+}
+
+func (c *counter) PostStop() {
+	c.events <- "stop"
+}
+
+func (c *counter) Receive(ctx *Context[counterMsg], msg counterMsg) error {
+	switch {
+	case msg.crash:
+		panic("boom")
+	case msg.get:
+		ctx.Respond(c.n)
+	default:
+		c.n += msg.add
+	}
+	return nil
+}
+
+// TestTellAskCase Tell修改状态，Ask读取状态，消息按顺序处理
+func TestTellAskCase(t *testing.T) {
+	sys := NewSystem(DefaultStrategy)
+	defer sys.Shutdown(context.Background())
+	events := make(chan string, 10)
+	ref, err := Spawn(sys, func() Receiver[counterMsg] { return &counter{events: events} }, Name("counter"), Bounded(2))
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, ref.Tell(ctx, counterMsg{add: 1}))
+	}
+	n, err := Ask[int](ctx, ref, counterMsg{get: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	// 没有回复的Ask
+	_, err = Ask[int](ctx, ref, counterMsg{add: 1})
+	assert.ErrorIs(t, err, ErrNoReply)
+
+	// 通过名字查找
+	found, ok := Lookup[counterMsg](sys, "counter")
+	assert.True(t, ok)
+	assert.Same(t, ref, found)
+	_, ok = Lookup[string](sys, "counter")
+	assert.False(t, ok)
+	_, err = Spawn(sys, func() Receiver[counterMsg] { return &counter{events: events} }, Name("counter"))
+	assert.ErrorIs(t, err, ErrNameTaken)
+
+	ref.Stop()
+	<-ref.Done()
+	assert.ErrorIs(t, ref.Tell(ctx, counterMsg{add: 1}), ErrStopped)
+	_, ok = Lookup[counterMsg](sys, "counter")
+	assert.False(t, ok)
+	assert.Equal(t, "start", <-events)
+	assert.Equal(t, "stop", <-events)
+}
+
+// TestRestartCase panic之后由监管策略重启，新实例的状态是全新的，钩子按顺序调用
+func TestRestartCase(t *testing.T) {
+	sys := NewSystem(Strategy{MaxRestarts: 1})
+	defer sys.Shutdown(context.Background())
+	events := make(chan string, 10)
+	ref, _ := Spawn(sys, func() Receiver[counterMsg] { return &counter{events: events} })
+	ctx := context.Background()
+
+	_ = ref.Tell(ctx, counterMsg{add: 5})
+	_, err := Ask[int](ctx, ref, counterMsg{crash: true})
+	assert.Contains(t, err.Error(), "panic: boom")
+	n, err := Ask[int](ctx, ref, counterMsg{get: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// 超过最大重启次数后停止
+	_ = ref.Tell(ctx, counterMsg{crash: true})
+	<-ref.Done()
+	close(events)
+	var got []string
+	for e := range events {
+		got = append(got, e)
+	}
+	assert.Equal(t, []string{"start", "stop", "start", "stop"}, got)
+}
+
+// flaky 第一次PreStart失败
+type flaky struct {
+	counter
+	failures *int
+}
+
+func (f *flaky) PreStart(ctx *Context[counterMsg]) error {
+	f.events <- "start"
+	if *f.failures > 0 {
+		*f.failures--
+		return errors.New("not ready")
+	}
+	return nil
+}
+
+// TestPreStartRestartCase PreStart失败只重启一次，失败的实例也会调用PostStop
+func TestPreStartRestartCase(t *testing.T) {
+	sys := NewSystem(Strategy{MaxRestarts: 3})
+	defer sys.Shutdown(context.Background())
+	events := make(chan string, 10)
+	// factory只在actor自己的goroutine里调用
+	created, failures := 0, 1
+	ref, _ := Spawn(sys, func() Receiver[counterMsg] {
+		created++
+		return &flaky{counter: counter{events: events}, failures: &failures}
+	})
+	n, err := Ask[int](context.Background(), ref, counterMsg{get: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	ref.Stop()
+	<-ref.Done()
+	close(events)
+	var got []string
+	for e := range events {
+		got = append(got, e)
+	}
+	assert.Equal(t, []string{"start", "stop", "start", "stop"}, got)
+	assert.Equal(t, 2, created)
+}
+
+// supervisor 启动时创建一个子actor，并把消息转发给它
+type supervisor struct {
+	child *Ref[counterMsg]
+}
+
+func (s *supervisor) PreStart(ctx *Context[counterMsg]) error {
+	child, err := SpawnChild(ctx, func() Receiver[counterMsg] { return &counter{events: make(chan string, 10)} })
+	s.child = child
+	return err
+}
+
+func (s *supervisor) Receive(ctx *Context[counterMsg], msg counterMsg) error {
+	return s.child.Tell(ctx, msg)
+}
+
+// TestEscalateCase 子actor的错误上报给父actor，父actor按照自己的监管者的策略处理
+func TestEscalateCase(t *testing.T) {
+	var mu sync.Mutex
+	var escalated []error
+	sys := NewSystem(Strategy{Decide: func(err error) Directive {
+		mu.Lock()
+		defer mu.Unlock()
+		escalated = append(escalated, err)
+		return Stop
+	}})
+	defer sys.Shutdown(context.Background())
+	parent, _ := Spawn(sys, func() Receiver[counterMsg] { return &supervisor{} },
+		Supervise(Strategy{Decide: func(err error) Directive { return Escalate }}))
+
+	_ = parent.Tell(context.Background(), counterMsg{crash: true})
+	select {
+	case <-parent.Done():
+	case <-time.After(time.Second):
+		t.Fatal("parent should stop after escalation")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, escalated, 1)
+	fmt.Println(escalated[0])
+}
+
+// TestShutdownCase Shutdown停止所有actor，之后不能再创建
+func TestShutdownCase(t *testing.T) {
+	sys := NewSystem(DefaultStrategy)
+	parent, _ := Spawn(sys, func() Receiver[counterMsg] { return &supervisor{} })
+	assert.NoError(t, sys.Shutdown(context.Background()))
+	<-parent.Done()
+	_, err := Spawn(sys, func() Receiver[counterMsg] { return &supervisor{} })
+	assert.True(t, errors.Is(err, ErrStopped))
+}
@@ -0,0 +1,78 @@
+package actor
+
+import (
+	"context"
+	"sync"
+)
+
+// mailbox actor的邮箱。size大于0时是有界的，满了之后Tell阻塞；size为0时无界，Tell永远不会阻塞
+type mailbox[M any] struct {
+	mu     sync.Mutex
+	queue  []envelope[M]
+	signal chan struct{}
+	slots  chan struct{}
+}
+
+type envelope[M any] struct {
+	msg   M
+	reply chan askReply
+}
+
+type askReply struct {
+	value any
+	err   error
+}
+
+func newMailbox[M any](size int) *mailbox[M] {
+	m := &mailbox[M]{signal: make(chan struct{}, 1)}
+	if size > 0 {
+		m.slots = make(chan struct{}, size)
+	}
+	return m
+}
+
+// put 放入消息，有界邮箱满了之后等待空位，直到ctx结束或者actor停止
+func (m *mailbox[M]) put(ctx context.Context, stopped <-chan struct{}, env envelope[M]) error {
+	if m.slots != nil {
+		select {
+		case m.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stopped:
+			return ErrStopped
+		}
+	}
+	m.mu.Lock()
+	m.queue = append(m.queue, env)
+	m.mu.Unlock()
+	select {
+	case m.signal <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pop 取出一条消息，没有消息时返回false
+func (m *mailbox[M]) pop() (envelope[M], bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.queue) == 0 {
+		return envelope[M]{}, false
+	}
+	env := m.queue[0]
+	m.queue[0] = envelope[M]{}
+	m.queue = m.queue[1:]
+	if m.slots != nil {
+		<-m.slots
+	}
+	return env, true
+}
+
+// drain 取出剩下的所有消息
+func (m *mailbox[M]) drain() []envelope[M] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rest := m.queue
+	m.queue = nil
+	return rest
+}
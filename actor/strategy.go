@@ -0,0 +1,40 @@
+package actor
+
+// Directive 监管者对失败的子actor做出的决定
+type Directive int
+
+const (
+	// Restart 丢弃出错的实例，用factory创建一个新实例继续处理邮箱里的消息
+	Restart Directive = iota
+	// Resume 忽略错误，保留当前实例和状态继续处理
+	Resume
+	// Stop 停止子actor
+	Stop
+	// Escalate 停止子actor，并把错误交给监管者自己的监管者处理
+	Escalate
+)
+
+// Strategy 监管策略
+type Strategy struct {
+	// Decide 根据错误做出决定，nil表示总是Restart
+	Decide func(err error) Directive
+	// MaxRestarts 每个子actor最多重启的次数，超过之后停止，0表示使用默认值3
+	MaxRestarts int
+}
+
+// DefaultStrategy 默认策略，出错就重启，最多重启3次
+var DefaultStrategy = Strategy{}
+
+func (s Strategy) decide(err error) Directive {
+	if s.Decide == nil {
+		return Restart
+	}
+	return s.Decide(err)
+}
+
+func (s Strategy) maxRestarts() int {
+	if s.MaxRestarts <= 0 {
+		return 3
+	}
+	return s.MaxRestarts
+}
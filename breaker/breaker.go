@@ -0,0 +1,264 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/pipeline"
+)
+
+// ErrOpen 熔断器处于打开状态，或者半开状态下试探请求的名额已经用完，请求被直接拒绝
+var ErrOpen = errors.New("breaker: circuit open")
+
+// State 熔断器的状态
+type State int
+
+const (
+	// Closed 正常放行，统计失败
+	Closed State = iota
+	// Open 直接拒绝所有请求，OpenTimeout之后进入HalfOpen
+	Open
+	// HalfOpen 放行少量试探请求，全部成功则关闭，任意一个失败则重新打开
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Settings 熔断器的配置，两个跳闸条件满足任意一个就会打开
+type Settings struct {
+	// ConsecutiveFailures 连续失败达到这个次数时跳闸，0表示不使用这个条件
+	ConsecutiveFailures int
+	// FailureRatio 失败率达到这个比例时跳闸，0表示不使用这个条件
+	FailureRatio float64
+	// MinRequests 请求数至少达到这个值才按失败率判断，避免刚开始一两次失败就跳闸
+	MinRequests int
+	// Interval 关闭状态下每隔多久清空一次统计，0表示只在状态变化时清空
+	Interval time.Duration
+	// OpenTimeout 打开之后多久进入半开状态，默认一分钟
+	OpenTimeout time.Duration
+	// HalfOpenProbes 半开状态下放行的试探请求数，默认1
+	HalfOpenProbes int
+	// IsFailure 判断错误是否计入失败，nil表示所有错误都算失败
+	IsFailure func(error) bool
+	// OnStateChange 状态变化时回调，在锁外调用
+	OnStateChange func(from, to State)
+	// Clock nil时使用真实时钟
+	Clock clock.Clock
+}
+
+// Counts 当前状态下的统计
+type Counts struct {
+	Requests            int
+	Failures            int
+	ConsecutiveFailures int
+}
+
+// Breaker 熔断器。下游挂掉的时候快速失败，不用每个请求都等到超时
+type Breaker struct {
+	s     Settings
+	clock clock.Clock
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	counts     Counts
+	expiry     time.Time
+	probes     int
+	// successes 半开状态下成功的试探数，Requests统计的是放行的试探数，包括还没有返回的
+	successes int
+}
+
+// New 创建一个处于关闭状态的熔断器
+func New(s Settings) *Breaker {
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = time.Minute
+	}
+	if s.HalfOpenProbes <= 0 {
+		s.HalfOpenProbes = 1
+	}
+	b := &Breaker{s: s, clock: clock.OrReal(s.Clock)}
+	b.toState(Closed, b.clock.Now())
+	return b
+}
+
+// State 当前状态，打开超时之后读取时会变成半开
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	state, changes := b.currentState(b.clock.Now())
+	b.mu.Unlock()
+	b.notify(changes)
+	return state
+}
+
+// Counts 当前状态下的统计
+func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.counts
+}
+
+// Do 通过熔断器执行fn，熔断器打开时直接返回ErrOpen
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := DoValue(ctx, b, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// DoValue 和Do一样，但是fn有返回值
+func DoValue[T any](ctx context.Context, b *Breaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	generation, err := b.before()
+	if err != nil {
+		return zero, err
+	}
+	v, err := fn(ctx)
+	b.after(generation, err)
+	return v, err
+}
+
+// Stage 把stage包装成经过熔断器的stage，熔断器打开时元素直接以ErrOpen作为错误结果往下游传，不再调用stage
+func Stage[In, Out any](b *Breaker, stage pipeline.Stage[In, Out]) pipeline.Stage[In, Out] {
+	return func(ctx context.Context, in In) (Out, error) {
+		return DoValue(ctx, b, func(ctx context.Context) (Out, error) {
+			return stage(ctx, in)
+		})
+	}
+}
+
+type change struct{ from, to State }
+
+func (b *Breaker) before() (uint64, error) {
+	b.mu.Lock()
+	state, changes := b.currentState(b.clock.Now())
+	var err error
+	switch state {
+	case Open:
+		err = ErrOpen
+	case HalfOpen:
+		if b.probes >= b.s.HalfOpenProbes {
+			err = ErrOpen
+		} else {
+			b.probes++
+		}
+	}
+	if err == nil {
+		b.counts.Requests++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	b.notify(changes)
+	return generation, err
+}
+
+func (b *Breaker) after(generation uint64, err error) {
+	failed := err != nil
+	if failed && b.s.IsFailure != nil {
+		failed = b.s.IsFailure(err)
+	}
+
+	b.mu.Lock()
+	now := b.clock.Now()
+	state, changes := b.currentState(now)
+	// 状态已经变过了，这个请求的结果属于上一轮，不再统计
+	if generation != b.generation {
+		b.mu.Unlock()
+		b.notify(changes)
+		return
+	}
+	if failed {
+		b.counts.Failures++
+		b.counts.ConsecutiveFailures++
+		if state == HalfOpen || b.shouldTrip() {
+			changes = append(changes, b.toState(Open, now))
+		}
+	} else {
+		b.counts.ConsecutiveFailures = 0
+		if state == HalfOpen {
+			b.successes++
+			if b.successes >= b.s.HalfOpenProbes {
+				changes = append(changes, b.toState(Closed, now))
+			}
+		}
+	}
+	b.mu.Unlock()
+	b.notify(changes)
+}
+
+func (b *Breaker) shouldTrip() bool {
+	if b.s.ConsecutiveFailures > 0 && b.counts.ConsecutiveFailures >= b.s.ConsecutiveFailures {
+		return true
+	}
+	if b.s.FailureRatio > 0 && b.counts.Requests >= b.s.MinRequests && b.counts.Requests > 0 {
+		return float64(b.counts.Failures)/float64(b.counts.Requests) >= b.s.FailureRatio
+	}
+	return false
+}
+
+// currentState 根据时间推进状态：打开超时进入半开，关闭状态下统计周期到了清空统计
+func (b *Breaker) currentState(now time.Time) (State, []change) {
+	var changes []change
+	switch b.state {
+	case Closed:
+		if !b.expiry.IsZero() && !now.Before(b.expiry) {
+			b.resetCounts(now)
+		}
+	case Open:
+		if !now.Before(b.expiry) {
+			changes = append(changes, b.toState(HalfOpen, now))
+		}
+	}
+	return b.state, changes
+}
+
+func (b *Breaker) toState(to State, now time.Time) change {
+	c := change{from: b.state, to: to}
+	b.state = to
+	b.resetCounts(now)
+	switch to {
+	case Open:
+		b.expiry = now.Add(b.s.OpenTimeout)
+	case HalfOpen:
+		b.expiry = time.Time{}
+	}
+	return c
+}
+
+func (b *Breaker) resetCounts(now time.Time) {
+	b.generation++
+	b.counts = Counts{}
+	b.probes = 0
+	b.successes = 0
+	b.expiry = time.Time{}
+	if b.state == Closed && b.s.Interval > 0 {
+		b.expiry = now.Add(b.s.Interval)
+	}
+}
+
+func (b *Breaker) notify(changes []change) {
+	if b.s.OnStateChange == nil {
+		return
+	}
+	for _, c := range changes {
+		if c.from != c.to {
+			b.s.OnStateChange(c.from, c.to)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+var errDown = errors.New("downstream down")
+
+func fail(ctx context.Context) error    { return errDown }
+func succeed(ctx context.Context) error { return nil }
+
+// TestBreakerLifecycleCase 连续失败跳闸 -> 打开时快速失败 -> 超时后半开 -> 试探成功后关闭
+func TestBreakerLifecycleCase(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	var changes []string
+	b := New(Settings{
+		ConsecutiveFailures: 3,
+		OpenTimeout:         10 * time.Second,
+		HalfOpenProbes:      2,
+		Clock:               fake,
+		OnStateChange: func(from, to State) {
+			changes = append(changes, fmt.Sprintf("%v->%v", from, to))
+		},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, b.Do(ctx, fail), errDown)
+	}
+	assert.Equal(t, Open, b.State())
+	// 打开状态下不再调用fn
+	called := false
+	err := b.Do(ctx, func(ctx context.Context) error { called = true; return nil })
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.False(t, called)
+
+	fake.Advance(10 * time.Second)
+	assert.Equal(t, HalfOpen, b.State())
+	assert.NoError(t, b.Do(ctx, succeed))
+	assert.Equal(t, HalfOpen, b.State())
+	assert.NoError(t, b.Do(ctx, succeed))
+	assert.Equal(t, Closed, b.State())
+
+	assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, changes)
+}
+
+// TestBreakerHalfOpenFailureCase 半开状态下试探失败，重新打开
+func TestBreakerHalfOpenFailureCase(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	b := New(Settings{ConsecutiveFailures: 1, OpenTimeout: time.Second, Clock: fake})
+	ctx := context.Background()
+	_ = b.Do(ctx, fail)
+	fake.Advance(time.Second)
+	assert.ErrorIs(t, b.Do(ctx, fail), errDown)
+	assert.Equal(t, Open, b.State())
+}
+
+// TestBreakerConcurrentProbesCase 多个试探同时进行时，第一个成功不会关闭熔断器，还在执行的试探失败时重新打开
+func TestBreakerConcurrentProbesCase(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	b := New(Settings{ConsecutiveFailures: 1, OpenTimeout: time.Second, HalfOpenProbes: 3, Clock: fake})
+	ctx := context.Background()
+	_ = b.Do(ctx, fail)
+	fake.Advance(time.Second)
+
+	results := []error{nil, errDown, errDown}
+	release := make([]chan struct{}, len(results))
+	done := make(chan error, len(results))
+	var started sync.WaitGroup
+	for i := range results {
+		release[i] = make(chan struct{})
+		started.Add(1)
+		go func(i int) {
+			done <- b.Do(ctx, func(ctx context.Context) error {
+				started.Done()
+				<-release[i]
+				return results[i]
+			})
+		}(i)
+	}
+	started.Wait()
+	// 名额已经用完
+	assert.ErrorIs(t, b.Do(ctx, succeed), ErrOpen)
+
+	close(release[0])
+	assert.NoError(t, <-done)
+	assert.Equal(t, HalfOpen, b.State())
+	close(release[1])
+	assert.ErrorIs(t, <-done, errDown)
+	assert.Equal(t, Open, b.State())
+	close(release[2])
+	<-done
+	assert.Equal(t, Open, b.State())
+}
+
+// TestBreakerFailureRatioCase 请求数达到MinRequests之后按失败率跳闸
+func TestBreakerFailureRatioCase(t *testing.T) {
+	b := New(Settings{FailureRatio: 0.5, MinRequests: 4})
+	ctx := context.Background()
+	_ = b.Do(ctx, succeed)
+	_ = b.Do(ctx, succeed)
+	_ = b.Do(ctx, fail)
+	// 只有三个请求，还不够判断
+	assert.Equal(t, Closed, b.State())
+	_ = b.Do(ctx, fail)
+	assert.Equal(t, Open, b.State())
+}
+
+// TestBreakerStageCase 在pipeline中熔断器打开后，后续元素直接得到ErrOpen，不再等待下游
+func TestBreakerStageCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	localeC := func(ctx context.Context, name string) (string, error) {
+		calls++
+		return "", errDown
+	}
+	b := New(Settings{ConsecutiveFailures: 2})
+
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for _, s := range []string{"a", "b", "c", "d"} {
+			in <- s
+		}
+	}()
+	var errs []error
+	for r := range pipeline.Map(ctx, in, Stage(b, localeC)) {
+		errs = append(errs, r.Err)
+	}
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []error{errDown, errDown, ErrOpen, ErrOpen}, errs)
+}
@@ -0,0 +1,182 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrClosed 往已经关闭的SafeChan发送数据
+	ErrClosed = errors.New("channel: send on closed SafeChan")
+	// ErrFull Offer时缓冲区已满
+	ErrFull = errors.New("channel: SafeChan is full")
+)
+
+// SafeChan 安全的通道。重复关闭、关闭后发送都不会panic，所以生产者和消费者可以按任意顺序退出，不需要约定谁来关闭通道
+type SafeChan[T any] struct {
+	ch   chan T
+	done chan struct{}
+	once sync.Once
+	mu   sync.RWMutex
+	shut bool
+}
+
+// NewSafeChan 创建缓冲区大小为size的SafeChan
+func NewSafeChan[T any](size int) *SafeChan[T] {
+	return &SafeChan[T]{ch: make(chan T, size), done: make(chan struct{})}
+}
+
+// TrySend 发送v，通道已经关闭或者在阻塞期间被关闭时返回ErrClosed
+func (c *SafeChan[T]) TrySend(ctx context.Context, v T) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.shut {
+		return ErrClosed
+	}
+	select {
+	case c.ch <- v:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Offer 不阻塞的发送，缓冲区满时返回ErrFull，通道已经关闭时返回ErrClosed
+func (c *SafeChan[T]) Offer(v T) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.shut {
+		return ErrClosed
+	}
+	select {
+	case c.ch <- v:
+		return nil
+	default:
+		return ErrFull
+	}
+}
+
+// Close 关闭通道，可以重复调用，重复关闭时返回ErrClosed
+func (c *SafeChan[T]) Close() error {
+	err := ErrClosed
+	c.once.Do(func() {
+		err = nil
+		// 先让阻塞中的TrySend退出，再拿写锁关闭，避免往已关闭的通道写
+		close(c.done)
+		c.mu.Lock()
+		c.shut = true
+		close(c.ch)
+		c.mu.Unlock()
+	})
+	return err
+}
+
+// Closed 是否已经关闭
+func (c *SafeChan[T]) Closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done 关闭时这个通道也会关闭，可以放在select里监听
+func (c *SafeChan[T]) Done() <-chan struct{} {
+	return c.done
+}
+
+// Out 只读视图，消费者从这里读取，关闭后剩余的数据读完就结束
+func (c *SafeChan[T]) Out() <-chan T {
+	return c.ch
+}
+
+// doneContext 把done通道转换成context，done关闭时context被取消
+func doneContext(done <-chan any) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// fanInTo fanIn写入SafeChan的版本。所有输入都读完后关闭out；消费者提前关闭out时，所有转发的goroutine都会退出
+// 输入同样可以是SafeChan的Out()，生产者关闭后这一路输入就结束了
+func fanInTo(done <-chan any, out *SafeChan[any], channels ...<-chan any) {
+	ctx, cancel := doneContext(done)
+	var wg sync.WaitGroup
+	multiplexed := func(c <-chan any) {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-out.Done():
+				return
+			case v, ok := <-c:
+				if !ok {
+					return
+				}
+				if out.TrySend(ctx, v) != nil {
+					return
+				}
+			}
+		}
+	}
+
+	wg.Add(len(channels))
+	for _, c := range channels {
+		go multiplexed(c)
+	}
+	go func() {
+		defer cancel()
+		wg.Wait()
+		out.Close()
+	}()
+}
+
+// bridgeTo bridge写入SafeChan的版本，消费者提前关闭out时停止读取chanStream
+func bridgeTo(done <-chan any, out *SafeChan[any], chanStream <-chan <-chan any) {
+	ctx, cancel := doneContext(done)
+	go func() {
+		defer cancel()
+		defer out.Close()
+		for {
+			var stream <-chan any
+			select {
+			case maybeStream, ok := <-chanStream:
+				if !ok {
+					return
+				}
+				stream = maybeStream
+			case <-ctx.Done():
+				return
+			case <-out.Done():
+				return
+			}
+		drain:
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-out.Done():
+					return
+				case v, ok := <-stream:
+					if !ok {
+						break drain
+					}
+					if out.TrySend(ctx, v) != nil {
+						return
+					}
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,98 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSafeChanCase 重复关闭、关闭后发送都不会panic
+func TestSafeChanCase(t *testing.T) {
+	c := NewSafeChan[int](1)
+	assert.NoError(t, c.TrySend(context.Background(), 1))
+	assert.NoError(t, c.Close())
+	assert.ErrorIs(t, c.Close(), ErrClosed)
+	assert.True(t, c.Closed())
+	assert.ErrorIs(t, c.TrySend(context.Background(), 2), ErrClosed)
+	<-c.Done()
+
+	var got []int
+	for v := range c.Out() {
+		got = append(got, v)
+	}
+	assert.Equal(t, []int{1}, got)
+}
+
+// TestSafeChanOfferCase Offer不阻塞，满了返回ErrFull
+func TestSafeChanOfferCase(t *testing.T) {
+	c := NewSafeChan[int](1)
+	assert.NoError(t, c.Offer(1))
+	assert.ErrorIs(t, c.Offer(2), ErrFull)
+	assert.Equal(t, 1, <-c.Out())
+	c.Close()
+	assert.ErrorIs(t, c.Offer(3), ErrClosed)
+}
+
+// TestFanInSafeChanCase SafeChan既作为fanIn的输入，也作为输出
+func TestFanInSafeChanCase(t *testing.T) {
+	done := make(chan any)
+	defer close(done)
+
+	in := NewSafeChan[any](0)
+	go func() {
+		defer in.Close()
+		for i := 0; i < 3; i++ {
+			if in.TrySend(context.Background(), i) != nil {
+				return
+			}
+		}
+	}()
+	out := NewSafeChan[any](0)
+	fanInTo(done, out, in.Out(), take(done, repeat(done, 10), 2))
+
+	var got []int
+	for v := range out.Out() {
+		got = append(got, v.(int))
+	}
+	sort.Ints(got)
+	assert.Equal(t, []int{0, 1, 2, 10, 10}, got)
+}
+
+// TestFanInConsumerCloseCase 消费者先关闭输出，fanIn里的goroutine和上游生产者都能退出
+func TestFanInConsumerCloseCase(t *testing.T) {
+	done := make(chan any)
+	defer close(done)
+
+	in := NewSafeChan[any](0)
+	producerExited := make(chan error)
+	go func() {
+		for {
+			if err := in.TrySend(context.Background(), "tick"); err != nil {
+				producerExited <- err
+				return
+			}
+		}
+	}()
+	out := NewSafeChan[any](0)
+	fanInTo(done, out, in.Out())
+	fmt.Println(<-out.Out())
+	out.Close()
+	// 下游已经不要数据了，关闭上游
+	in.Close()
+	assert.ErrorIs(t, <-producerExited, ErrClosed)
+}
+
+// TestBridgeSafeChanCase bridge写入SafeChan
+func TestBridgeSafeChanCase(t *testing.T) {
+	out := NewSafeChan[any](0)
+	bridgeTo(nil, out, genVals())
+	count := 0
+	for v := range out.Out() {
+		fmt.Printf("从brideg中拿到的值 %v \n", v)
+		count++
+	}
+	assert.Equal(t, 10, count)
+}
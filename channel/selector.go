@@ -0,0 +1,316 @@
+package channel
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	"concurrenceWay/internal/chans"
+)
+
+// SelectMode 多个case同时就绪时怎么选择
+type SelectMode int
+
+const (
+	// Random 和原生select一样随机选择
+	Random SelectMode = iota
+	// Priority 优先选择优先级高的case，只有高优先级的都没有数据时才看低优先级的
+	Priority
+	// Weighted 按权重比例选择，权重为2的case被选中的次数大约是权重为1的两倍
+	Weighted
+)
+
+// CaseID Add返回的case编号，Select用它告诉调用方是哪个case触发了
+type CaseID int
+
+// CaseOption Add的可选项
+type CaseOption func(*selCase)
+
+// WithPriority 设置优先级，数值越大越优先，只在Priority模式下生效
+func WithPriority(p int) CaseOption {
+	return func(c *selCase) { c.priority = p }
+}
+
+// WithWeight 设置权重，默认1，只在Weighted模式下生效
+func WithWeight(w int) CaseOption {
+	return func(c *selCase) {
+		if w > 0 {
+			c.weight = w
+		}
+	}
+}
+
+type selCase struct {
+	id       CaseID
+	ch       reflect.Value
+	priority int
+	weight   int
+	// current 平滑加权轮询的当前值
+	current int
+	// age 连续没有被选中的次数
+	age int
+}
+
+// SelectorOption NewSelector的可选项
+type SelectorOption func(*selectorOptions)
+
+type selectorOptions struct {
+	aging           bool
+	starvationLimit int
+}
+
+// Aging Priority模式下，case每次没有被选中有效优先级加一，被选中后恢复。低优先级的case只会被推迟，不会被饿死
+func Aging() SelectorOption {
+	return func(o *selectorOptions) { o.aging = true }
+}
+
+// StarvationLimit Priority模式下，一个case连续n次没有被选中之后，下一次先尝试它，它没有数据时再按优先级选择
+func StarvationLimit(n int) SelectorOption {
+	return func(o *selectorOptions) { o.starvationLimit = n }
+}
+
+// Selector 动态select。case的数量在运行时可以随时增减，不用像fanIn那样每个输入一个goroutine。
+// Add和Remove可以在任意goroutine调用，Select同一时间只能由一个goroutine调用
+type Selector[T any] struct {
+	mode SelectMode
+	opts selectorOptions
+
+	mu      sync.Mutex
+	cases   []*selCase
+	nextID  CaseID
+	version int
+	// wake Select阻塞期间case发生了变化，通知它重新构建
+	wake chan struct{}
+
+	// 以下字段只在Select的goroutine中使用，case没有变化时复用，减少分配
+	builtVersion int
+	builtDone    <-chan struct{}
+	snapshot     []*selCase
+	selectCases  []reflect.SelectCase
+}
+
+// NewSelector 创建一个Selector
+func NewSelector[T any](mode SelectMode, opts ...SelectorOption) *Selector[T] {
+	s := &Selector[T]{mode: mode, wake: make(chan struct{}, 1), builtVersion: -1}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+	return s
+}
+
+// Add 添加一个通道，返回它的编号
+func (s *Selector[T]) Add(ch <-chan T, opts ...CaseOption) CaseID {
+	c := &selCase{ch: reflect.ValueOf(ch), weight: 1}
+	for _, opt := range opts {
+		opt(c)
+	}
+	s.mu.Lock()
+	c.id = s.nextID
+	s.nextID++
+	s.cases = append(s.cases, c)
+	if s.mode == Priority {
+		sort.SliceStable(s.cases, func(i, j int) bool { return s.cases[i].priority > s.cases[j].priority })
+	}
+	s.changed()
+	s.mu.Unlock()
+	return c.id
+}
+
+// Remove 移除一个通道，不存在时返回false
+func (s *Selector[T]) Remove(id CaseID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.cases {
+		if c.id == id {
+			s.cases = append(s.cases[:i:i], s.cases[i+1:]...)
+			s.changed()
+			return true
+		}
+	}
+	return false
+}
+
+// Len 当前的case数量
+func (s *Selector[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.cases)
+}
+
+func (s *Selector[T]) changed() {
+	s.version++
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Select 等待任意一个case就绪，返回触发的case编号和值。ok为false表示这个通道已经关闭，它已经被自动移除了。
+// 没有case时一直等待，直到有新的case加入或者ctx结束
+func (s *Selector[T]) Select(ctx context.Context) (id CaseID, v T, ok bool, err error) {
+	for {
+		s.rebuild(ctx)
+		var chosen int
+		var recv reflect.Value
+		switch s.mode {
+		case Priority:
+			chosen, recv, ok = s.selectInOrder(s.priorityOrder())
+		case Weighted:
+			chosen, recv, ok = s.selectInOrder(s.weightedOrder())
+		default:
+			chosen = -1
+		}
+		if chosen < 0 {
+			// 没有立即就绪的case，阻塞等待所有case
+			chosen, recv, ok = reflect.Select(s.selectCases)
+			switch chosen {
+			case 0:
+				return 0, v, false, ctx.Err()
+			case 1:
+				continue
+			}
+			chosen -= 2
+		}
+
+		c := s.snapshot[chosen]
+		if !ok {
+			s.Remove(c.id)
+			return c.id, v, false, nil
+		}
+		switch s.mode {
+		case Priority:
+			s.age(c)
+		case Weighted:
+			s.charge(c)
+		}
+		return c.id, chans.Cast[T](recv.Interface()), true, nil
+	}
+}
+
+// rebuild case有变化时重新构建reflect.SelectCase，前两个固定是ctx.Done()和wake
+func (s *Selector[T]) rebuild(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.builtVersion == s.version && s.builtDone == ctx.Done() {
+		return
+	}
+	s.builtVersion = s.version
+	s.builtDone = ctx.Done()
+	s.snapshot = append(s.snapshot[:0], s.cases...)
+	s.selectCases = append(s.selectCases[:0],
+		reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.wake)},
+	)
+	for _, c := range s.snapshot {
+		s.selectCases = append(s.selectCases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: c.ch})
+	}
+}
+
+// selectInOrder 按order的顺序逐个非阻塞地尝试，返回第一个就绪的case在snapshot中的下标，都没就绪时返回-1
+func (s *Selector[T]) selectInOrder(order []*selCase) (int, reflect.Value, bool) {
+	for _, c := range order {
+		// 不能立即接收时v是无效值，通道关闭时v是有效的零值并且ok为false
+		v, ok := c.ch.TryRecv()
+		if v.IsValid() {
+			for i, sc := range s.snapshot {
+				if sc == c {
+					return i, v, ok
+				}
+			}
+		}
+	}
+	return -1, reflect.Value{}, false
+}
+
+// priorityOrder snapshot已经按优先级排好序，老化和饿死保护需要考虑age重新排序
+func (s *Selector[T]) priorityOrder() []*selCase {
+	if !s.opts.aging && s.opts.starvationLimit <= 0 {
+		return s.snapshot
+	}
+	order := make([]*selCase, len(s.snapshot))
+	copy(order, s.snapshot)
+	rank := func(c *selCase) int {
+		if s.opts.aging {
+			return c.priority + c.age
+		}
+		return c.priority
+	}
+	starving := func(c *selCase) bool {
+		return s.opts.starvationLimit > 0 && c.age >= s.opts.starvationLimit
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if starving(a) != starving(b) {
+			return starving(a)
+		}
+		if starving(a) && a.age != b.age {
+			return a.age > b.age
+		}
+		if rank(a) != rank(b) {
+			return rank(a) > rank(b)
+		}
+		return a.priority > b.priority
+	})
+	return order
+}
+
+// age 被选中的case的age清零，其余的加一
+func (s *Selector[T]) age(chosen *selCase) {
+	for _, c := range s.snapshot {
+		if c == chosen {
+			c.age = 0
+		} else {
+			c.age++
+		}
+	}
+}
+
+// weightedOrder 平滑加权轮询：每个case的current加上自己的权重，按current从大到小尝试
+func (s *Selector[T]) weightedOrder() []*selCase {
+	order := make([]*selCase, len(s.snapshot))
+	copy(order, s.snapshot)
+	for _, c := range order {
+		c.current += c.weight
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].current > order[j].current })
+	return order
+}
+
+// charge 被选中的case减去总权重
+func (s *Selector[T]) charge(chosen *selCase) {
+	total := 0
+	for _, c := range s.snapshot {
+		total += c.weight
+	}
+	chosen.current -= total
+}
+
+// fanInSelect 用Selector实现的fanIn，只用一个goroutine读取所有输入
+func fanInSelect(done <-chan any, channels ...<-chan any) <-chan any {
+	multiplexedStream := make(chan any)
+	go func() {
+		defer close(multiplexedStream)
+		ctx, cancel := doneContext(done)
+		defer cancel()
+		sel := NewSelector[any](Random)
+		for _, c := range channels {
+			sel.Add(c)
+		}
+		for sel.Len() > 0 {
+			_, v, ok, err := sel.Select(ctx)
+			if err != nil {
+				return
+			}
+			if !ok {
+				continue
+			}
+			select {
+			case <-done:
+				return
+			case multiplexedStream <- v:
+			}
+		}
+	}()
+	return multiplexedStream
+}
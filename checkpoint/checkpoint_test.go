@@ -0,0 +1,131 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTrackerCase 乱序确认时检查点停在最早没有确认的位置
+func TestTrackerCase(t *testing.T) {
+	tr := NewTracker(100)
+	assert.Equal(t, uint64(100), tr.Checkpoint())
+	tr.Track(100, 110)
+	tr.Track(110, 125)
+	tr.Track(125, 130)
+	assert.NoError(t, tr.Ack(110))
+	assert.Equal(t, uint64(100), tr.Checkpoint())
+	assert.NoError(t, tr.Ack(100))
+	assert.Equal(t, uint64(125), tr.Checkpoint())
+	assert.NoError(t, tr.Ack(125))
+	assert.Equal(t, uint64(130), tr.Checkpoint())
+	assert.Equal(t, 0, tr.InFlight())
+	assert.Error(t, tr.Ack(7))
+}
+
+// TestFileStoreCase 保存之后可以读回来，没有保存过时ok为false
+func TestFileStoreCase(t *testing.T) {
+	s := FileStore{Dir: t.TempDir()}
+	_, ok, err := s.Load("import")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, s.Save("import", 42))
+	assert.NoError(t, s.Save("import", 43))
+	pos, ok, err := s.Load("import")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(43), pos)
+}
+
+// TestManagerPeriodicFlushCase 按Interval定期保存
+func TestManagerPeriodicFlushCase(t *testing.T) {
+	s := FileStore{Dir: t.TempDir()}
+	fake := clock.NewFake(time.Now())
+	m, err := NewManager(s, "job", Interval(time.Second), WithClock(fake))
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+	fake.BlockUntil(1)
+
+	m.Track(0, 1)
+	m.Track(1, 2)
+	assert.NoError(t, m.Ack(0))
+	fake.Advance(time.Second)
+	assert.Eventually(t, func() bool {
+		pos, _, _ := s.Load("job")
+		return pos == 1
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, m.Ack(1))
+	cancel()
+	assert.NoError(t, <-done)
+	pos, _, _ := s.Load("job")
+	assert.Equal(t, uint64(2), pos)
+}
+
+// TestRunResumeCase 第一次处理到一半失败，重启之后从检查点继续，所有元素至少处理一次
+func TestRunResumeCase(t *testing.T) {
+	store := FileStore{Dir: t.TempDir()}
+	text := "a\nb\nc\nd\ne\nf\n"
+	boom := errors.New("boom")
+
+	var mu sync.Mutex
+	seen := map[string]int{}
+	handler := func(failOn string) Handler[string] {
+		return func(ctx context.Context, rec Record[string]) error {
+			if rec.Value == failOn {
+				return boom
+			}
+			mu.Lock()
+			seen[rec.Value]++
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	m, err := NewManager(store, "lines")
+	assert.NoError(t, err)
+	err = Run(context.Background(), m, Lines(strings.NewReader(text)), 1, handler("d"))
+	assert.Equal(t, boom, err)
+	pos, _, _ := store.Load("lines")
+	assert.Equal(t, uint64(6), pos, "resume at the start of d")
+
+	m, err = NewManager(store, "lines")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), m.Start())
+	err = Run(context.Background(), m, Lines(strings.NewReader(text)), 3, handler(""))
+	assert.NoError(t, err)
+	pos, _, _ = store.Load("lines")
+	assert.Equal(t, uint64(len(text)), pos)
+	for _, v := range []string{"a", "b", "c", "d", "e", "f"} {
+		assert.Equal(t, 1, seen[v], v)
+	}
+}
+
+// TestSkipAppliedCase 幂等sink记录已经写过的位置，重复的元素被跳过但仍然确认
+func TestSkipAppliedCase(t *testing.T) {
+	store := FileStore{Dir: t.TempDir()}
+	values := []int{10, 20, 30, 40}
+	// sink已经写到了下标2，但是检查点只保存到了1
+	assert.NoError(t, store.Save("sum", 1))
+	applied := uint64(2)
+	var writes []int
+	sink := SkipApplied(func(pos uint64) bool { return pos <= applied }, func(ctx context.Context, rec Record[int]) error {
+		writes = append(writes, rec.Value)
+		applied = rec.Pos
+		return nil
+	})
+	m, err := NewManager(store, "sum")
+	assert.NoError(t, err)
+	assert.NoError(t, Run(context.Background(), m, Slice(values), 1, sink))
+	assert.Equal(t, []int{40}, writes)
+	pos, _, _ := store.Load("sum")
+	assert.Equal(t, uint64(4), pos)
+}
@@ -0,0 +1,96 @@
+package checkpoint
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"concurrenceWay/clock"
+)
+
+// Option Manager的可选项
+type Option func(*Manager)
+
+// Interval 定期保存检查点的间隔，默认5秒
+func Interval(d time.Duration) Option {
+	return func(m *Manager) { m.interval = d }
+}
+
+// WithClock 指定时钟，测试时传入clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(m *Manager) { m.clock = c }
+}
+
+// Manager 跟踪一个pipeline的处理进度，定期把可以安全恢复的位置保存到Store
+type Manager struct {
+	store    Store
+	name     string
+	interval time.Duration
+	clock    clock.Clock
+	start    uint64
+	tracker  *Tracker
+
+	mu    sync.Mutex
+	saved uint64
+}
+
+// NewManager 从store读取name上次保存的检查点，没有时从0开始
+func NewManager(store Store, name string, opts ...Option) (*Manager, error) {
+	m := &Manager{store: store, name: name, interval: 5 * time.Second}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.clock = clock.OrReal(m.clock)
+	pos, _, err := store.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	m.start, m.saved = pos, pos
+	m.tracker = NewTracker(pos)
+	return m, nil
+}
+
+// Start 恢复的起点，source应该从这里开始读
+func (m *Manager) Start() uint64 { return m.start }
+
+// Track 记录source发出的一个元素，必须按发出的顺序调用
+func (m *Manager) Track(pos, next uint64) { m.tracker.Track(pos, next) }
+
+// Ack 确认pos处的元素处理完了
+func (m *Manager) Ack(pos uint64) error { return m.tracker.Ack(pos) }
+
+// Checkpoint 当前可以安全恢复的位置，还没有保存
+func (m *Manager) Checkpoint() uint64 { return m.tracker.Checkpoint() }
+
+// Flush 检查点有变化时立即保存
+func (m *Manager) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pos := m.tracker.Checkpoint()
+	if pos == m.saved {
+		return nil
+	}
+	if err := m.store.Save(m.name, pos); err != nil {
+		return err
+	}
+	m.saved = pos
+	return nil
+}
+
+// Run 每隔Interval调用一次Flush，ctx取消时最后Flush一次并返回。保存失败时下次继续尝试，返回最后一次的错误
+func (m *Manager) Run(ctx context.Context) error {
+	ticker := m.clock.NewTicker(m.interval)
+	defer ticker.Stop()
+	var last error
+	for {
+		select {
+		case <-ctx.Done():
+			if err := m.Flush(); err != nil {
+				return err
+			}
+			return last
+		case <-ticker.C():
+			last = m.Flush()
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package checkpoint
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Source 可以从指定位置开始读的source，ctx取消时停止并关闭通道
+type Source[T any] func(ctx context.Context, from uint64) <-chan Record[T]
+
+// Handler 处理一个元素。重启之后检查点之后的元素会再被处理一次，即至少一次，Handler需要根据Pos做到幂等
+type Handler[T any] func(ctx context.Context, rec Record[T]) error
+
+// SkipApplied 幂等sink的hook：applied返回true说明这个位置的结果已经写过了，直接确认，不再调用h。
+// 例如sink把最后写入的Pos和数据在同一个事务里保存，applied就是pos < 保存的Pos+1
+func SkipApplied[T any](applied func(pos uint64) bool, h Handler[T]) Handler[T] {
+	return func(ctx context.Context, rec Record[T]) error {
+		if applied(rec.Pos) {
+			return nil
+		}
+		return h(ctx, rec)
+	}
+}
+
+// Run 从m.Start()开始读src，用workers个goroutine执行h，成功的元素被确认，m在后台定期保存检查点。
+// src读完时保存最终的检查点并返回nil。h或者src出错时停止，出错的元素没有被确认，下次从它开始
+func Run[T any](ctx context.Context, m *Manager, src Source[T], workers int, h Handler[T]) error {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var first error
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+
+	flushCtx, stopFlush := context.WithCancel(context.Background())
+	flushed := make(chan error, 1)
+	go func() { flushed <- m.Run(flushCtx) }()
+
+	jobs := make(chan Record[T])
+	go func() {
+		defer close(jobs)
+		for rec := range src(ctx, m.Start()) {
+			if rec.Err != nil {
+				fail(rec.Err)
+				return
+			}
+			// 先Track再交给worker，否则worker可能先Ack
+			m.Track(rec.Pos, rec.Next)
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- rec:
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rec := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := h(ctx, rec); err != nil {
+					fail(err)
+					continue
+				}
+				if err := m.Ack(rec.Pos); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	stopFlush()
+	flushErr := <-flushed
+	if first != nil {
+		return first
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// Slice 位置是下标
+func Slice[T any](s []T) Source[T] {
+	return func(ctx context.Context, from uint64) <-chan Record[T] {
+		out := make(chan Record[T])
+		go func() {
+			defer close(out)
+			for i := from; i < uint64(len(s)); i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- Record[T]{Pos: i, Next: i + 1, Value: s[i]}:
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// Lines 按行读取r，位置是行首的字节偏移，输出的行不包含换行符
+func Lines(r io.ReadSeeker) Source[string] {
+	return func(ctx context.Context, from uint64) <-chan Record[string] {
+		out := make(chan Record[string])
+		go func() {
+			defer close(out)
+			emit := func(rec Record[string]) bool {
+				select {
+				case <-ctx.Done():
+					return false
+				case out <- rec:
+					return true
+				}
+			}
+			if _, err := r.Seek(int64(from), io.SeekStart); err != nil {
+				emit(Record[string]{Pos: from, Err: err})
+				return
+			}
+			br := bufio.NewReader(r)
+			pos := from
+			for {
+				line, err := br.ReadString('\n')
+				if len(line) > 0 {
+					next := pos + uint64(len(line))
+					text := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+					if !emit(Record[string]{Pos: pos, Next: next, Value: text}) {
+						return
+					}
+					pos = next
+				}
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					emit(Record[string]{Pos: pos, Err: err})
+					return
+				}
+			}
+		}()
+		return out
+	}
+}
@@ -0,0 +1,73 @@
+package checkpoint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Store 保存每个pipeline的检查点
+type Store interface {
+	// Load 读取name的检查点，没有保存过时ok为false
+	Load(name string) (pos uint64, ok bool, err error)
+	// Save 保存name的检查点，要么保存成功要么保持原来的值
+	Save(name string, pos uint64) error
+}
+
+// FileStore 本地目录里每个name一个文件
+type FileStore struct {
+	Dir string
+}
+
+func (s FileStore) path(name string) string {
+	return filepath.Join(s.Dir, name+".ckpt")
+}
+
+func (s FileStore) Load(name string) (uint64, bool, error) {
+	data, err := os.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	pos, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("checkpoint: parse %s: %w", s.path(name), err)
+	}
+	return pos, true, nil
+}
+
+// Save 先写临时文件并fsync，再rename覆盖，崩溃时不会留下写了一半的检查点
+func (s FileStore) Save(name string, pos uint64) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.Dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.WriteString(strconv.FormatUint(pos, 10) + "\n")
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path(name))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	d, err := os.Open(s.Dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
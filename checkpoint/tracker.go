@@ -0,0 +1,80 @@
+package checkpoint
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Record 可恢复的source输出的元素。Pos是重新读到这个元素需要的位置，Next是它后面一个元素的位置。
+// 位置可以是下标、文件偏移等任何随着读取单调增加的值，不要求连续
+type Record[T any] struct {
+	Pos   uint64
+	Next  uint64
+	Value T
+	// Err source读取出错，Run遇到时停止
+	Err error
+}
+
+type entry struct {
+	pos   uint64
+	next  uint64
+	acked bool
+}
+
+// Tracker 记录已经发出但还没有确认的位置，计算可以安全恢复的位置：最早一个没有确认的元素的Pos，
+// 都确认了时是最后一个元素的Next。元素必须按发出的顺序Track，Ack可以乱序
+type Tracker struct {
+	mu      sync.Mutex
+	pending []*entry
+	index   map[uint64]*entry
+	done    uint64
+}
+
+// NewTracker start是恢复的起点，还没有Track任何元素时Checkpoint返回它
+func NewTracker(start uint64) *Tracker {
+	return &Tracker{index: map[uint64]*entry{}, done: start}
+}
+
+// Track 记录一个发出的元素
+func (t *Tracker) Track(pos, next uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := &entry{pos: pos, next: next}
+	t.pending = append(t.pending, e)
+	t.index[pos] = e
+}
+
+// Ack 确认pos处的元素已经处理完
+func (t *Tracker) Ack(pos uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.index[pos]
+	if !ok {
+		return fmt.Errorf("checkpoint: ack of untracked position %d", pos)
+	}
+	e.acked = true
+	delete(t.index, pos)
+	for len(t.pending) > 0 && t.pending[0].acked {
+		t.done = t.pending[0].next
+		t.pending[0] = nil
+		t.pending = t.pending[1:]
+	}
+	return nil
+}
+
+// Checkpoint 可以安全恢复的位置，从这里重新读不会漏掉任何没有确认的元素
+func (t *Tracker) Checkpoint() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) > 0 {
+		return t.pending[0].pos
+	}
+	return t.done
+}
+
+// InFlight 还没有确认的元素个数
+func (t *Tracker) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
@@ -0,0 +1,31 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFakeAdvanceCase Fake时钟只有Advance之后定时器才会触发，ticker消费不及时会丢弃多余的tick
+func TestFakeAdvanceCase(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+	timer := fake.NewTimer(3 * time.Second)
+	ticker := fake.NewTicker(time.Second)
+	assert.Equal(t, 2, fake.Waiters())
+
+	fake.Advance(2 * time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired too early")
+	default:
+	}
+	assert.Equal(t, start.Add(time.Second), <-ticker.C())
+
+	fake.Advance(time.Second)
+	assert.Equal(t, start.Add(3*time.Second), <-timer.C())
+	assert.Equal(t, start.Add(3*time.Second), <-ticker.C())
+	ticker.Stop()
+	assert.Equal(t, 0, fake.Waiters())
+}
@@ -0,0 +1,184 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake 手动推进的时钟。只有调用Advance时，定时器和ticker才会触发
+// 测试里一般先BlockUntil等到被测goroutine注册好定时器，再Advance，这样就不会有时序上的竞争
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	until  time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewFake 创建一个从start开始的Fake时钟
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{period: d, c: make(chan time.Time, 1)}
+	f.mu.Lock()
+	w.until = f.now.Add(d)
+	f.add(w)
+	f.mu.Unlock()
+	return &fakeTicker{f: f, w: w}
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{c: make(chan time.Time, 1)}
+	f.mu.Lock()
+	w.until = f.now.Add(d)
+	f.add(w)
+	f.fire()
+	f.mu.Unlock()
+	return &fakeTimer{f: f, w: w}
+}
+
+// Advance 把时间向前推进d，期间到期的定时器按到期顺序依次触发
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	target := f.now.Add(d)
+	for {
+		next := f.earliest()
+		if next == nil || next.until.After(target) {
+			break
+		}
+		f.now = next.until
+		f.fire()
+	}
+	f.now = target
+}
+
+// BlockUntil 阻塞直到至少有n个定时器或ticker在等待
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// Waiters 当前在等待的定时器和ticker数量
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+func (f *Fake) add(w *fakeWaiter) {
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+}
+
+func (f *Fake) remove(w *fakeWaiter) bool {
+	for i, o := range f.waiters {
+		if o == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fake) earliest() *fakeWaiter {
+	var next *fakeWaiter
+	for _, w := range f.waiters {
+		if next == nil || w.until.Before(next.until) {
+			next = w
+		}
+	}
+	return next
+}
+
+// fire 触发所有已到期的定时器，和真实的ticker一样，消费者跟不上时丢弃多余的tick
+func (f *Fake) fire() {
+	for _, w := range append([]*fakeWaiter(nil), f.waiters...) {
+		if w.until.After(f.now) {
+			continue
+		}
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		if w.period > 0 {
+			w.until = w.until.Add(w.period)
+		} else {
+			f.remove(w)
+		}
+	}
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.c }
+
+func (t *fakeTicker) Stop() {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.remove(t.w)
+	t.w.period = d
+	t.w.until = t.f.now.Add(d)
+	t.f.add(t.w)
+}
+
+type fakeTimer struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.w.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.remove(t.w)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	active := t.f.remove(t.w)
+	t.w.until = t.f.now.Add(d)
+	t.f.add(t.w)
+	t.f.fire()
+	return active
+}
@@ -0,0 +1,102 @@
+package constraint
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBulkheadFull 分区的并发数和等待队列都已经满了，调用被拒绝
+var ErrBulkheadFull = errors.New("constraint: bulkhead full")
+
+// Partition 分区的配置
+type Partition struct {
+	// MaxConcurrent 分区内同时执行的最大调用数
+	MaxConcurrent int64
+	// MaxWaiting 最多允许多少个调用排队等待，0表示不排队，满了就直接拒绝；小于0表示不限制
+	MaxWaiting int
+}
+
+// BulkheadStats 分区的统计
+type BulkheadStats struct {
+	Active   int64
+	Waiting  int64
+	Rejected int64
+}
+
+// Bulkhead 舱壁隔离。按名字把调用分到不同的分区，每个分区单独限制并发，一个下游变慢只会占满自己分区的名额，不会拖垮其他分区
+type Bulkhead struct {
+	defaults Partition
+
+	mu         sync.Mutex
+	partitions map[string]*partition
+}
+
+type partition struct {
+	cfg   Partition
+	sem   *Semaphore
+	stats BulkheadStats
+}
+
+// NewBulkhead 创建舱壁，没有单独配置过的分区使用defaults
+func NewBulkhead(defaults Partition) *Bulkhead {
+	return &Bulkhead{defaults: defaults, partitions: make(map[string]*partition)}
+}
+
+// Configure 单独配置某个分区，需要在该分区第一次使用之前调用
+func (b *Bulkhead) Configure(name string, cfg Partition) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.partitions[name] = &partition{cfg: cfg, sem: NewSemaphore(cfg.MaxConcurrent)}
+}
+
+// Do 在name分区中执行fn。分区满了并且等待队列也满了时返回ErrBulkheadFull，等待期间ctx结束时返回ctx的错误，两种情况都计入Rejected
+func (b *Bulkhead) Do(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	b.mu.Lock()
+	p := b.partition(name)
+	if !p.sem.TryAcquire(1) {
+		if p.cfg.MaxWaiting >= 0 && p.stats.Waiting >= int64(p.cfg.MaxWaiting) {
+			p.stats.Rejected++
+			b.mu.Unlock()
+			return ErrBulkheadFull
+		}
+		p.stats.Waiting++
+		b.mu.Unlock()
+
+		err := p.sem.Acquire(ctx, 1)
+
+		b.mu.Lock()
+		p.stats.Waiting--
+		if err != nil {
+			p.stats.Rejected++
+			b.mu.Unlock()
+			return err
+		}
+	}
+	p.stats.Active++
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		p.stats.Active--
+		b.mu.Unlock()
+		p.sem.Release(1)
+	}()
+	return fn(ctx)
+}
+
+// Stats 分区当前的统计
+func (b *Bulkhead) Stats(name string) BulkheadStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.partition(name).stats
+}
+
+func (b *Bulkhead) partition(name string) *partition {
+	p, ok := b.partitions[name]
+	if !ok {
+		p = &partition{cfg: b.defaults, sem: NewSemaphore(b.defaults.MaxConcurrent)}
+		b.partitions[name] = p
+	}
+	return p
+}
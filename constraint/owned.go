@@ -0,0 +1,70 @@
+package constraint
+
+import (
+	"context"
+	"errors"
+
+	"concurrenceWay/channel"
+)
+
+var (
+	// ErrClosed 往已经关闭的Owned通道写数据，或者重复关闭
+	ErrClosed = errors.New("constraint: owned channel closed")
+	// ErrNotOwner 非owner的goroutine写数据或者关闭，只有在confinedebug构建标签下才会检查
+	ErrNotOwner = errors.New("constraint: write from non-owner goroutine")
+)
+
+// Writer Owned通道的写入端，只有拥有Writer的一方可以写入和关闭，从语法上保证了只有一个写入者。
+// 关闭后写入不会panic的部分由channel.SafeChan实现，这里只负责owner检查
+type Writer[T any] struct {
+	ch    *channel.SafeChan[T]
+	owner owner
+}
+
+// Owned 词法约束的通道。返回写入端和只读视图，调用方把只读视图交给消费者，写入端留给自己，这样写入和关闭的职责就被限定在一处。
+// 使用confinedebug构建标签时，会记录创建Writer的goroutine，其他goroutine写入或关闭时返回ErrNotOwner
+func Owned[T any](size int) (*Writer[T], <-chan T) {
+	w := &Writer[T]{ch: channel.NewSafeChan[T](size), owner: newOwner()}
+	return w, w.ch.Out()
+}
+
+// Produce 在新的goroutine中创建Owned通道并执行fn，fn返回后自动关闭。Writer在写入的goroutine中创建，所以confinedebug下的检查可以通过
+func Produce[T any](size int, fn func(w *Writer[T])) <-chan T {
+	views := make(chan (<-chan T))
+	go func() {
+		w, view := Owned[T](size)
+		defer w.Close()
+		views <- view
+		fn(w)
+	}()
+	return <-views
+}
+
+// Send 写入v。关闭后写入返回ErrClosed而不是panic，阻塞期间被关闭也会返回ErrClosed
+func (w *Writer[T]) Send(ctx context.Context, v T) error {
+	if err := w.owner.check(); err != nil {
+		return err
+	}
+	return closedErr(w.ch.TrySend(ctx, v))
+}
+
+// Close 关闭通道，只会真正关闭一次，重复关闭返回ErrClosed
+func (w *Writer[T]) Close() error {
+	if err := w.owner.check(); err != nil {
+		return err
+	}
+	return closedErr(w.ch.Close())
+}
+
+// Closed 是否已经关闭
+func (w *Writer[T]) Closed() bool {
+	return w.ch.Closed()
+}
+
+// closedErr 把channel.ErrClosed换成本包的ErrClosed
+func closedErr(err error) error {
+	if errors.Is(err, channel.ErrClosed) {
+		return ErrClosed
+	}
+	return err
+}
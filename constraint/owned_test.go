@@ -0,0 +1,43 @@
+package constraint
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOwnedCase 用Produce改写TestConstraintCase，写入端只在生产者的goroutine里可见
+func TestOwnedCase(t *testing.T) {
+	data := []int{1, 2, 3, 4}
+	handleData := Produce(0, func(w *Writer[int]) {
+		for i := range data {
+			if err := w.Send(context.Background(), data[i]); err != nil {
+				return
+			}
+		}
+	})
+
+	var got []int
+	for num := range handleData {
+		fmt.Println(num)
+		got = append(got, num)
+	}
+	assert.Equal(t, data, got)
+}
+
+// TestOwnedCloseCase 只关闭一次，关闭后写入返回错误而不是panic
+func TestOwnedCloseCase(t *testing.T) {
+	w, view := Owned[string](1)
+	assert.NoError(t, w.Send(context.Background(), "a"))
+	assert.NoError(t, w.Close())
+	assert.ErrorIs(t, w.Close(), ErrClosed)
+	assert.ErrorIs(t, w.Send(context.Background(), "b"), ErrClosed)
+	assert.True(t, w.Closed())
+
+	// 关闭前写入的数据还能读到
+	assert.Equal(t, "a", <-view)
+	_, ok := <-view
+	assert.False(t, ok)
+}
@@ -0,0 +1,10 @@
+//go:build !confinedebug
+
+package constraint
+
+// owner 非调试构建下不做任何检查
+type owner struct{}
+
+func newOwner() owner { return owner{} }
+
+func (owner) check() error { return nil }
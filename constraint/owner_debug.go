@@ -0,0 +1,36 @@
+//go:build confinedebug
+
+package constraint
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+// owner 记录创建Writer的goroutine，只在调试时使用，解析堆栈获取goroutine id的开销不小
+type owner struct {
+	id uint64
+}
+
+func newOwner() owner {
+	return owner{id: goroutineID()}
+}
+
+func (o owner) check() error {
+	if id := goroutineID(); id != o.id {
+		return fmt.Errorf("%w: owner goroutine %d, caller goroutine %d", ErrNotOwner, o.id, id)
+	}
+	return nil
+}
+
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// 堆栈的第一行形如 "goroutine 18 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	buf = buf[:bytes.IndexByte(buf, ' ')]
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
@@ -0,0 +1,27 @@
+//go:build confinedebug
+
+package constraint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOwnerCheckCase confinedebug下，其他goroutine写入和关闭都会被发现
+// go test -tags confinedebug ./constraint
+func TestOwnerCheckCase(t *testing.T) {
+	w, _ := Owned[int](1)
+	errs := make(chan error, 2)
+	go func() {
+		errs <- w.Send(context.Background(), 1)
+		errs <- w.Close()
+	}()
+	assert.ErrorIs(t, <-errs, ErrNotOwner)
+	assert.ErrorIs(t, <-errs, ErrNotOwner)
+
+	// owner自己写入没问题
+	assert.NoError(t, w.Send(context.Background(), 1))
+	assert.NoError(t, w.Close())
+}
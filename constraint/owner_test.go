@@ -0,0 +1,22 @@
+//go:build !confinedebug
+
+package constraint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOwnedBlockedSendCase 阻塞中的Send在关闭时返回ErrClosed
+// 需要从另一个goroutine关闭，confinedebug下会被判定为越权，所以只在非调试构建下运行
+func TestOwnedBlockedSendCase(t *testing.T) {
+	w, _ := Owned[int](0)
+	errs := make(chan error)
+	go func() { errs <- w.Send(context.Background(), 1) }()
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, w.Close())
+	assert.ErrorIs(t, <-errs, ErrClosed)
+}
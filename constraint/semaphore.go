@@ -0,0 +1,121 @@
+package constraint
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Semaphore 带权重的信号量，一次可以获取n个名额。
+// 等待者严格按照先来后到的顺序获取，排在前面的大请求没满足之前，后面的小请求也不会插队，这样大请求不会被饿死
+type Semaphore struct {
+	size    int64
+	cur     int64
+	mu      sync.Mutex
+	waiters list.List
+}
+
+type semWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// NewSemaphore 创建一个总共有n个名额的信号量
+func NewSemaphore(n int64) *Semaphore {
+	return &Semaphore{size: n}
+}
+
+// Acquire 获取n个名额，名额不够时阻塞，直到获取成功或者ctx结束。ctx结束时不会占用任何名额
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	done := ctx.Done()
+	s.mu.Lock()
+	select {
+	case <-done:
+		s.mu.Unlock()
+		return ctx.Err()
+	default:
+	}
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	if n > s.size {
+		// 永远不可能满足，只能等ctx结束
+		s.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(semWaiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// ctx结束的同时已经拿到了名额，当作成功处理
+			s.mu.Unlock()
+			return nil
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// 排在最前面的大请求放弃了，后面的请求可能可以被满足了
+			if isFront && s.size > s.cur {
+				s.notifyWaiters()
+			}
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+	case <-ready:
+		return nil
+	}
+}
+
+// TryAcquire 不阻塞地获取n个名额，获取失败返回false
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release 归还n个名额，归还的比获取的多时panic
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	if s.cur < 0 {
+		panic("constraint: semaphore released more than held")
+	}
+	s.notifyWaiters()
+}
+
+// Waiting 正在等待的请求数
+func (s *Semaphore) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiters.Len()
+}
+
+func (s *Semaphore) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(semWaiter)
+		if s.size-s.cur < w.n {
+			// 队首的请求满足不了就停下，不让后面的小请求插队
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,103 @@
+package constraint
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSemaphoreWeightedCase 带权重的获取和释放
+func TestSemaphoreWeightedCase(t *testing.T) {
+	s := NewSemaphore(10)
+	ctx := context.Background()
+	assert.NoError(t, s.Acquire(ctx, 7))
+	assert.False(t, s.TryAcquire(4))
+	assert.True(t, s.TryAcquire(3))
+	s.Release(10)
+	assert.True(t, s.TryAcquire(10))
+	s.Release(10)
+}
+
+// TestSemaphoreFIFOCase 先排队的大请求没有满足之前，后来的小请求不能插队
+func TestSemaphoreFIFOCase(t *testing.T) {
+	s := NewSemaphore(4)
+	ctx := context.Background()
+	assert.NoError(t, s.Acquire(ctx, 3))
+
+	var mu sync.Mutex
+	var order []int64
+	var wg sync.WaitGroup
+	acquire := func(n int64) {
+		defer wg.Done()
+		assert.NoError(t, s.Acquire(ctx, n))
+		mu.Lock()
+		order = append(order, n)
+		mu.Unlock()
+		s.Release(n)
+	}
+	wg.Add(1)
+	go acquire(4)
+	for s.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	// 还剩1个名额，但是前面有人在排队，所以TryAcquire也拿不到
+	assert.False(t, s.TryAcquire(1))
+	wg.Add(1)
+	go acquire(1)
+	for s.Waiting() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	s.Release(3)
+	wg.Wait()
+	assert.Equal(t, []int64{4, 1}, order)
+}
+
+// TestSemaphoreCancelCase 等待时ctx结束，不占用名额，后面的请求可以继续获取
+func TestSemaphoreCancelCase(t *testing.T) {
+	s := NewSemaphore(2)
+	assert.NoError(t, s.Acquire(context.Background(), 1))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Acquire(ctx, 2), context.DeadlineExceeded)
+	assert.Equal(t, 0, s.Waiting())
+	assert.True(t, s.TryAcquire(1))
+}
+
+// TestBulkheadCase 分区满了之后排队，排队也满了就拒绝，其他分区不受影响
+func TestBulkheadCase(t *testing.T) {
+	b := NewBulkhead(Partition{MaxConcurrent: 1, MaxWaiting: 1})
+	ctx := context.Background()
+	release := make(chan struct{})
+	running := make(chan struct{})
+	slow := func(ctx context.Context) error {
+		running <- struct{}{}
+		<-release
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); assert.NoError(t, b.Do(ctx, "db", slow)) }()
+	<-running
+	go func() { defer wg.Done(); assert.NoError(t, b.Do(ctx, "db", slow)) }()
+	for b.Stats("db").Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	err := b.Do(ctx, "db", slow)
+	assert.True(t, errors.Is(err, ErrBulkheadFull))
+	assert.Equal(t, BulkheadStats{Active: 1, Waiting: 1, Rejected: 1}, b.Stats("db"))
+
+	// 其他分区不受影响
+	assert.NoError(t, b.Do(ctx, "cache", func(ctx context.Context) error { return nil }))
+
+	close(release)
+	<-running
+	wg.Wait()
+	assert.Equal(t, BulkheadStats{Rejected: 1}, b.Stats("db"))
+}
@@ -0,0 +1,41 @@
+package durable
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec 元素和字节之间的转换，写入日志之前编码，读出之后解码
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// Gob 用encoding/gob编解码，只能在Go程序之间使用，字段可以是接口(需要gob.Register)
+type Gob[T any] struct{}
+
+func (Gob[T]) Encode(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Gob[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+// JSON 用encoding/json编解码，日志文件可以被其它语言读取，只有导出的字段会被保存
+type JSON[T any] struct{}
+
+func (JSON[T]) Encode(v T) ([]byte, error) { return json.Marshal(v) }
+
+func (JSON[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
@@ -0,0 +1,478 @@
+package durable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"concurrenceWay/clock"
+)
+
+var (
+	// ErrClosed 队列已经关闭
+	ErrClosed = errors.New("durable: queue closed")
+	// ErrNotDelivered Ack了一个还没有被Read读出的offset
+	ErrNotDelivered = errors.New("durable: offset not delivered")
+	// ErrDecode 记录完整但是Codec解码失败
+	ErrDecode = errors.New("durable: decode failed")
+)
+
+// SyncPolicy 什么时候把写入的数据fsync到磁盘
+type SyncPolicy int
+
+const (
+	// SyncEveryWrite 每次Append都fsync，最安全也最慢
+	SyncEveryWrite SyncPolicy = iota
+	// SyncPeriodic 距离上次fsync超过SyncInterval时，在Append中fsync。机器掉电时最多丢失一个间隔内的数据
+	SyncPeriodic
+	// SyncNever 只在Close和Sync时fsync，进程崩溃不会丢数据，机器掉电可能丢
+	SyncNever
+)
+
+const commitFile = "commit"
+
+// Options 队列的配置，只有Dir是必须的
+type Options struct {
+	// Dir 存放segment和提交位置的目录，不存在时创建。同一个目录同时只能被一个Queue打开
+	Dir string
+	// SegmentSize 当前segment超过这个大小时切换到新的segment，默认16MB
+	SegmentSize int64
+	// Sync fsync策略，默认SyncEveryWrite
+	Sync SyncPolicy
+	// SyncInterval SyncPeriodic的间隔，默认1秒
+	SyncInterval time.Duration
+	// CommitOnAck 每次Ack让连续Ack的位置前进时立即提交，不需要再调用Commit，代价是每次Ack都要写一次提交文件
+	CommitOnAck bool
+	// Clock 测试时传入clock.Fake
+	Clock clock.Clock
+}
+
+// Message Read读出的元素和它在日志中的位置，处理完之后用Offset调用Ack
+type Message[T any] struct {
+	Offset uint64
+	Value  T
+}
+
+// Queue 基于本地追加写日志的持久化队列，只有一个消费者。
+// 元素Append之后按顺序被Read读出，消费者处理完调用Ack，Commit把连续Ack过的最大位置写入磁盘。
+// 重启之后从提交的位置继续读，已经Ack但还没Commit的元素会被再次读出。CommitOnAck可以把这个窗口缩小到单个元素，
+// 但处理完和Ack之间崩溃的元素仍然会被再次读出，所以投递语义是至少一次，不重复处理需要下游幂等，
+// 例如用checkpoint.SkipApplied跳过已经生效的元素
+type Queue[T any] struct {
+	opts  Options
+	codec Codec[T]
+
+	mu       sync.Mutex
+	closed   bool
+	segs     []*segment
+	active   *os.File
+	next     uint64
+	lastSync time.Time
+	// notify Append时关闭并换成新的，唤醒等待中的Read
+	notify chan struct{}
+
+	readOff uint64
+	readSeg int
+	readPos int64
+	reader  *os.File
+
+	acked     map[uint64]struct{}
+	ackNext   uint64
+	committed uint64
+}
+
+// Open 打开dir中的队列，不存在时创建。最后一个segment末尾不完整的记录(崩溃时没写完)会被截断
+func Open[T any](opts Options, codec Codec[T]) (*Queue[T], error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = 16 << 20
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = time.Second
+	}
+	opts.Clock = clock.OrReal(opts.Clock)
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	committed, err := readCommit(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	segs, err := listSegments(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 {
+		segs = []*segment{{base: committed, path: segmentPath(opts.Dir, committed)}}
+	}
+	for i, s := range segs {
+		count, valid, err := scan(s.path)
+		switch {
+		case err == nil:
+		case errors.Is(err, errTorn) && i == len(segs)-1:
+			if err := os.Truncate(s.path, valid); err != nil {
+				return nil, err
+			}
+		case errors.Is(err, errTorn):
+			return nil, fmt.Errorf("%w: %s at %d", ErrCorrupt, s.path, valid)
+		case os.IsNotExist(err):
+			// 新的队列，segment文件还没有创建
+		default:
+			return nil, err
+		}
+		s.count, s.size = count, valid
+		if i > 0 && segs[i-1].base+segs[i-1].count != s.base {
+			return nil, fmt.Errorf("%w: gap before %s", ErrCorrupt, s.path)
+		}
+	}
+	last := segs[len(segs)-1]
+	q := &Queue[T]{
+		opts:     opts,
+		codec:    codec,
+		segs:     segs,
+		next:     last.base + last.count,
+		lastSync: opts.Clock.Now(),
+		notify:   make(chan struct{}),
+		acked:    map[uint64]struct{}{},
+	}
+	if committed < segs[0].base {
+		committed = segs[0].base
+	}
+	if committed > q.next {
+		return nil, fmt.Errorf("%w: commit %d beyond end %d", ErrCorrupt, committed, q.next)
+	}
+	q.committed, q.ackNext = committed, committed
+	if q.active, err = os.OpenFile(last.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return nil, err
+	}
+	if err := q.seek(committed); err != nil {
+		q.active.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// seek 把读取位置移到off
+func (q *Queue[T]) seek(off uint64) error {
+	i := len(q.segs) - 1
+	for j, s := range q.segs {
+		if off < s.base+s.count {
+			i = j
+			break
+		}
+	}
+	if err := q.openReader(i); err != nil {
+		return err
+	}
+	for q.readOff = q.segs[i].base; q.readOff < off; q.readOff++ {
+		_, n, err := readRecord(q.reader, q.readPos)
+		if err != nil {
+			return err
+		}
+		q.readPos += n
+	}
+	return nil
+}
+
+func (q *Queue[T]) openReader(i int) error {
+	if q.reader != nil {
+		q.reader.Close()
+	}
+	f, err := os.Open(q.segs[i].path)
+	if err != nil {
+		return err
+	}
+	q.reader, q.readSeg, q.readPos = f, i, 0
+	return nil
+}
+
+// Append 写入一个元素，返回它的offset。按Sync策略决定是否fsync
+func (q *Queue[T]) Append(v T) (uint64, error) {
+	data, err := q.codec.Encode(v)
+	if err != nil {
+		return 0, err
+	}
+	rec := encodeRecord(data)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, ErrClosed
+	}
+	last := q.segs[len(q.segs)-1]
+	if last.size >= q.opts.SegmentSize && last.count > 0 {
+		if err := q.rotate(); err != nil {
+			return 0, err
+		}
+		last = q.segs[len(q.segs)-1]
+	}
+	if _, err := q.active.Write(rec); err != nil {
+		return 0, err
+	}
+	last.size += int64(len(rec))
+	last.count++
+	off := q.next
+	q.next++
+
+	switch q.opts.Sync {
+	case SyncEveryWrite:
+		err = q.sync()
+	case SyncPeriodic:
+		if q.opts.Clock.Since(q.lastSync) >= q.opts.SyncInterval {
+			err = q.sync()
+		}
+	}
+	close(q.notify)
+	q.notify = make(chan struct{})
+	return off, err
+}
+
+// rotate 关闭当前segment，开始一个新的segment
+func (q *Queue[T]) rotate() error {
+	if err := q.sync(); err != nil {
+		return err
+	}
+	if err := q.active.Close(); err != nil {
+		return err
+	}
+	s := &segment{base: q.next, path: segmentPath(q.opts.Dir, q.next)}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	q.active = f
+	q.segs = append(q.segs, s)
+	return syncDir(q.opts.Dir)
+}
+
+func (q *Queue[T]) sync() error {
+	q.lastSync = q.opts.Clock.Now()
+	return q.active.Sync()
+}
+
+// Sync 立即fsync当前segment
+func (q *Queue[T]) Sync() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	return q.sync()
+}
+
+// Read 读出下一个元素，没有时阻塞直到有新的Append、ctx取消或者队列关闭。
+// 解码失败时返回带着Offset的Message和错误，读取位置照样前进，消费者可以Ack跳过它
+func (q *Queue[T]) Read(ctx context.Context) (Message[T], error) {
+	return q.read(ctx, nil)
+}
+
+// read idle关闭并且已经读到末尾时返回io.EOF
+func (q *Queue[T]) read(ctx context.Context, idle <-chan struct{}) (Message[T], error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return Message[T]{}, ErrClosed
+		}
+		if q.readOff < q.next {
+			m, err := q.readNext()
+			q.mu.Unlock()
+			return m, err
+		}
+		notify := q.notify
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return Message[T]{}, ctx.Err()
+		case <-notify:
+		case <-idle:
+			idle = nil
+			// 再检查一次，idle关闭之前可能有新的Append
+			q.mu.Lock()
+			caughtUp := q.readOff >= q.next
+			q.mu.Unlock()
+			if caughtUp {
+				return Message[T]{}, io.EOF
+			}
+		}
+	}
+}
+
+func (q *Queue[T]) readNext() (Message[T], error) {
+	if q.readPos >= q.segs[q.readSeg].size {
+		if err := q.openReader(q.readSeg + 1); err != nil {
+			return Message[T]{}, err
+		}
+	}
+	data, n, err := readRecord(q.reader, q.readPos)
+	if err != nil {
+		return Message[T]{}, err
+	}
+	m := Message[T]{Offset: q.readOff}
+	q.readPos += n
+	q.readOff++
+	if m.Value, err = q.codec.Decode(data); err != nil {
+		return m, fmt.Errorf("%w: offset %d: %v", ErrDecode, m.Offset, err)
+	}
+	return m, nil
+}
+
+// Ack 标记offset已经处理完，可以乱序Ack。只有连续Ack过的部分才会被Commit
+func (q *Queue[T]) Ack(offset uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if offset >= q.readOff {
+		return fmt.Errorf("%w: %d", ErrNotDelivered, offset)
+	}
+	if offset < q.ackNext {
+		return nil
+	}
+	q.acked[offset] = struct{}{}
+	for {
+		if _, ok := q.acked[q.ackNext]; !ok {
+			break
+		}
+		delete(q.acked, q.ackNext)
+		q.ackNext++
+	}
+	if q.opts.CommitOnAck {
+		return q.commit()
+	}
+	return nil
+}
+
+// Commit 把连续Ack过的位置写入磁盘，重启之后从这里开始读
+func (q *Queue[T]) Commit() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	return q.commit()
+}
+
+// commit 调用时必须持有q.mu
+func (q *Queue[T]) commit() error {
+	if q.ackNext == q.committed {
+		return nil
+	}
+	if err := writeCommit(q.opts.Dir, q.ackNext); err != nil {
+		return err
+	}
+	q.committed = q.ackNext
+	return nil
+}
+
+// Committed 已经提交的位置，小于它的元素都处理完了
+func (q *Queue[T]) Committed() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.committed
+}
+
+// Len 已经写入但还没有被Read读出的元素个数
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int(q.next - q.readOff)
+}
+
+// Compact 删除所有记录都已经提交的segment，当前正在写的segment不会被删除。返回删除的segment个数
+func (q *Queue[T]) Compact() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, ErrClosed
+	}
+	n := 0
+	for n < len(q.segs)-1 && n < q.readSeg {
+		s := q.segs[n]
+		if s.base+s.count > q.committed {
+			break
+		}
+		if err := os.Remove(s.path); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	q.segs = q.segs[n:]
+	q.readSeg -= n
+	return n, syncDir(q.opts.Dir)
+}
+
+// Close fsync并关闭队列，阻塞中的Read返回ErrClosed。没有Commit的Ack会丢失
+func (q *Queue[T]) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	q.closed = true
+	close(q.notify)
+	err := q.active.Sync()
+	if cerr := q.active.Close(); err == nil {
+		err = cerr
+	}
+	if q.reader != nil {
+		q.reader.Close()
+	}
+	return err
+}
+
+func readCommit(dir string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, commitFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	off, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: commit file: %v", ErrCorrupt, err)
+	}
+	return off, nil
+}
+
+// writeCommit 先写临时文件再rename，崩溃时要么是旧的位置要么是新的位置
+func writeCommit(dir string, off uint64) error {
+	tmp := filepath.Join(dir, commitFile+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(strconv.FormatUint(off, 10) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, commitFile)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir 让文件的创建、删除和rename落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
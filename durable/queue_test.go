@@ -0,0 +1,287 @@
+package durable
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	ID   int
+	Name string
+}
+
+func open(t *testing.T, dir string, opts ...func(*Options)) *Queue[record] {
+	o := Options{Dir: dir}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	q, err := Open[record](o, JSON[record]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func readN(t *testing.T, q *Queue[record], n int) []Message[record] {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var got []Message[record]
+	for i := 0; i < n; i++ {
+		m, err := q.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m)
+	}
+	return got
+}
+
+// TestQueueResumeCase 重启之后从提交的位置继续读，Ack了但没有Commit的会再读一次
+func TestQueueResumeCase(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir)
+	for i := 0; i < 5; i++ {
+		off, err := q.Append(record{ID: i})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(i), off)
+	}
+	got := readN(t, q, 3)
+	assert.Equal(t, record{ID: 2}, got[2].Value)
+	assert.NoError(t, q.Ack(0))
+	assert.NoError(t, q.Ack(1))
+	assert.NoError(t, q.Commit())
+	assert.NoError(t, q.Ack(2))
+	assert.True(t, errors.Is(q.Ack(3), ErrNotDelivered))
+	assert.NoError(t, q.Close())
+
+	q = open(t, dir)
+	defer q.Close()
+	assert.Equal(t, uint64(2), q.Committed())
+	assert.Equal(t, 3, q.Len())
+	got = readN(t, q, 3)
+	assert.Equal(t, uint64(2), got[0].Offset)
+	assert.Equal(t, record{ID: 4}, got[2].Value)
+	off, err := q.Append(record{ID: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), off)
+}
+
+// TestQueueCommitOnAckCase CommitOnAck时不调用Commit，重启之后也不会再读出已经Ack的元素
+func TestQueueCommitOnAckCase(t *testing.T) {
+	dir := t.TempDir()
+	commitOnAck := func(o *Options) { o.CommitOnAck = true }
+	q := open(t, dir, commitOnAck)
+	for i := 0; i < 3; i++ {
+		_, err := q.Append(record{ID: i})
+		assert.NoError(t, err)
+	}
+	readN(t, q, 2)
+	assert.NoError(t, q.Ack(1))
+	assert.Equal(t, uint64(0), q.Committed())
+	assert.NoError(t, q.Ack(0))
+	assert.Equal(t, uint64(2), q.Committed())
+	assert.NoError(t, q.Close())
+
+	q = open(t, dir, commitOnAck)
+	defer q.Close()
+	got := readN(t, q, 1)
+	assert.Equal(t, uint64(2), got[0].Offset)
+}
+
+// TestQueueOutOfOrderAckCase 乱序Ack时只提交连续的部分
+func TestQueueOutOfOrderAckCase(t *testing.T) {
+	q := open(t, t.TempDir())
+	defer q.Close()
+	for i := 0; i < 4; i++ {
+		q.Append(record{ID: i})
+	}
+	readN(t, q, 4)
+	assert.NoError(t, q.Ack(0))
+	assert.NoError(t, q.Ack(2))
+	assert.NoError(t, q.Ack(3))
+	assert.NoError(t, q.Commit())
+	assert.Equal(t, uint64(1), q.Committed())
+	assert.NoError(t, q.Ack(1))
+	assert.NoError(t, q.Commit())
+	assert.Equal(t, uint64(4), q.Committed())
+}
+
+// TestQueueRotateCompactCase segment写满之后切换，提交之后旧的segment可以删除
+func TestQueueRotateCompactCase(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir, func(o *Options) { o.SegmentSize = 64; o.Sync = SyncNever })
+	for i := 0; i < 20; i++ {
+		_, err := q.Append(record{ID: i, Name: "rotate"})
+		assert.NoError(t, err)
+	}
+	segs, _ := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	assert.Greater(t, len(segs), 3)
+
+	for _, m := range readN(t, q, 15) {
+		assert.NoError(t, q.Ack(m.Offset))
+	}
+	assert.NoError(t, q.Commit())
+	n, err := q.Compact()
+	assert.NoError(t, err)
+	assert.Greater(t, n, 0)
+	left, _ := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	assert.Equal(t, len(segs)-n, len(left))
+	assert.NoError(t, q.Close())
+
+	// 删除之后重新打开，剩下的元素还在
+	q = open(t, dir, func(o *Options) { o.SegmentSize = 64 })
+	defer q.Close()
+	got := readN(t, q, 5)
+	assert.Equal(t, uint64(15), got[0].Offset)
+	assert.Equal(t, 19, got[4].Value.ID)
+}
+
+// TestQueueTornTailCase 崩溃时最后一条记录没写完，打开时截断
+func TestQueueTornTailCase(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir)
+	for i := 0; i < 3; i++ {
+		q.Append(record{ID: i})
+	}
+	assert.NoError(t, q.Close())
+
+	path := segmentPath(dir, 0)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	assert.NoError(t, err)
+	f.Write(encodeRecord([]byte(`{"ID":3}`))[:10])
+	f.Close()
+
+	q = open(t, dir)
+	defer q.Close()
+	assert.Equal(t, 3, q.Len())
+	off, err := q.Append(record{ID: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), off)
+	got := readN(t, q, 4)
+	assert.Equal(t, 3, got[3].Value.ID)
+}
+
+// TestQueueCorruptCase 中间的segment损坏时不能自动修复
+func TestQueueCorruptCase(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, dir, func(o *Options) { o.SegmentSize = 16 })
+	for i := 0; i < 3; i++ {
+		q.Append(record{ID: i})
+	}
+	assert.NoError(t, q.Close())
+	path := segmentPath(dir, 0)
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xff
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+
+	_, err := Open[record](Options{Dir: dir}, JSON[record]{})
+	assert.True(t, errors.Is(err, ErrCorrupt))
+}
+
+// TestQueueReadBlockCase Read等待新的Append，ctx取消和Close都会让它返回
+func TestQueueReadBlockCase(t *testing.T) {
+	q := open(t, t.TempDir())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Append(record{ID: 7})
+	}()
+	m, err := q.Read(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 7, m.Value.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = q.Read(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Close()
+	}()
+	_, err = q.Read(context.Background())
+	assert.Equal(t, ErrClosed, err)
+}
+
+// TestCodecCase gob和JSON都能还原
+func TestCodecCase(t *testing.T) {
+	for _, codec := range []Codec[record]{Gob[record]{}, JSON[record]{}} {
+		data, err := codec.Encode(record{ID: 1, Name: "a"})
+		assert.NoError(t, err)
+		v, err := codec.Decode(data)
+		assert.NoError(t, err)
+		assert.Equal(t, record{ID: 1, Name: "a"}, v)
+	}
+
+	q, err := Open[record](Options{Dir: t.TempDir()}, Gob[record]{})
+	assert.NoError(t, err)
+	defer q.Close()
+	q.Append(record{ID: 9})
+	m, err := q.Read(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 9, m.Value.ID)
+}
+
+// TestBufferCase 处理到一半重启，没有提交的元素再输出一次，不会丢也不会重复已经提交的
+func TestBufferCase(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	q := open(t, dir)
+	for i := 0; i < 10; i++ {
+		q.Append(record{ID: i})
+	}
+	out := Buffer(ctx, q, make(chan record))
+	for i := 0; i < 4; i++ {
+		r := <-out
+		assert.NoError(t, r.Err)
+		assert.Equal(t, i, r.Value.Value.ID)
+		assert.NoError(t, q.Ack(r.Value.Offset))
+	}
+	assert.NoError(t, q.Commit())
+	// 模拟崩溃
+	cancel()
+	for range out {
+	}
+	assert.NoError(t, q.Close())
+
+	q = open(t, dir)
+	defer q.Close()
+	rest := make(chan record)
+	close(rest)
+	var ids []int
+	for r := range Buffer(context.Background(), q, rest) {
+		assert.NoError(t, r.Err)
+		ids = append(ids, r.Value.Value.ID)
+		q.Ack(r.Value.Offset)
+	}
+	assert.NoError(t, q.Commit())
+	assert.Equal(t, []int{4, 5, 6, 7, 8, 9}, ids)
+}
+
+// TestBufferDrainCase in关闭并且q读完之后输出关闭
+func TestBufferDrainCase(t *testing.T) {
+	q := open(t, t.TempDir(), func(o *Options) { o.Sync = SyncPeriodic })
+	defer q.Close()
+	in := make(chan record)
+	go func() {
+		defer close(in)
+		for i := 0; i < 100; i++ {
+			in <- record{ID: i}
+		}
+	}()
+	n := 0
+	for r := range Buffer(context.Background(), q, in) {
+		assert.NoError(t, r.Err)
+		assert.Equal(t, n, r.Value.Value.ID)
+		assert.NoError(t, q.Ack(r.Value.Offset))
+		n++
+	}
+	assert.Equal(t, 100, n)
+	assert.NoError(t, q.Commit())
+	assert.Equal(t, uint64(100), q.Committed())
+}
@@ -0,0 +1,123 @@
+package durable
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 日志由若干个segment文件组成，文件名是这个segment第一条记录的offset。每条记录的格式是
+// [4字节长度][4字节crc32][数据]，长度和crc都是大端序
+
+const (
+	headerSize    = 8
+	segmentSuffix = ".seg"
+	// maxRecordSize 防止损坏的长度字段导致分配过大的内存
+	maxRecordSize = 64 << 20
+)
+
+// ErrCorrupt 除最后一个segment以外的segment中有损坏的记录，不能自动修复
+var ErrCorrupt = errors.New("durable: corrupt segment")
+
+// errTorn 记录不完整或者crc不对，最后一个segment末尾出现时是崩溃时没写完，截断即可
+var errTorn = errors.New("durable: torn record")
+
+type segment struct {
+	base  uint64
+	path  string
+	size  int64
+	count uint64
+}
+
+func segmentPath(dir string, base uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", base, segmentSuffix))
+}
+
+// listSegments 按base从小到大列出dir中的segment
+func listSegments(dir string) ([]*segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []*segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, &segment{base: base, path: filepath.Join(dir, name)})
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].base < segs[j].base })
+	return segs, nil
+}
+
+// scan 数一遍segment里的完整记录，返回记录数和最后一条完整记录结束的位置
+func scan(path string) (count uint64, valid int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	for {
+		_, n, err := readRecord(f, valid)
+		if err == io.EOF {
+			return count, valid, nil
+		}
+		if err != nil {
+			return count, valid, err
+		}
+		valid += n
+		count++
+	}
+}
+
+// readRecord 读取pos处的一条记录，返回数据和记录的总长度。正好在文件末尾时返回io.EOF
+func readRecord(r io.ReaderAt, pos int64) ([]byte, int64, error) {
+	var header [headerSize]byte
+	n, err := r.ReadAt(header[:], pos)
+	if n == 0 && err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	if n < headerSize {
+		if err == io.EOF {
+			return nil, 0, errTorn
+		}
+		return nil, 0, err
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if size > maxRecordSize {
+		return nil, 0, errTorn
+	}
+	data := make([]byte, size)
+	if n, err := r.ReadAt(data, pos+headerSize); n < int(size) {
+		if err == io.EOF {
+			return nil, 0, errTorn
+		}
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(data) != sum {
+		return nil, 0, errTorn
+	}
+	return data, headerSize + int64(size), nil
+}
+
+// encodeRecord 加上长度和crc
+func encodeRecord(data []byte) []byte {
+	rec := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.ChecksumIEEE(data))
+	copy(rec[headerSize:], data)
+	return rec
+}
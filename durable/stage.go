@@ -0,0 +1,65 @@
+package durable
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"concurrenceWay/pipeline"
+)
+
+// Buffer 持久化版本的buffer：in中的元素先Append到q，再从q中Read出来交给下游，进程崩溃时已经写入q的元素不会丢失。
+// 下游处理完一个元素之后调用q.Ack(m.Offset)，打开q时设置了CommitOnAck就不需要再调用q.Commit。
+// 重启之后用同一个目录Open，Buffer会先输出上次没有提交的元素，即至少一次。in关闭并且q中的元素都读完之后输出关闭
+func Buffer[T any](ctx context.Context, q *Queue[T], in <-chan T) <-chan pipeline.Result[Message[T]] {
+	out := make(chan pipeline.Result[Message[T]])
+	emit := func(r pipeline.Result[Message[T]]) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case out <- r:
+			return true
+		}
+	}
+
+	written := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(written)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if _, err := q.Append(v); err != nil {
+					emit(pipeline.Result[Message[T]]{Err: err})
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			m, err := q.read(ctx, written)
+			if errors.Is(err, io.EOF) || errors.Is(err, ErrClosed) || ctx.Err() != nil {
+				return
+			}
+			// 解码失败的元素交给下游决定是否Ack跳过，其它错误是磁盘出了问题，不能继续读
+			if !emit(pipeline.Result[Message[T]]{Value: m, Err: err}) || (err != nil && !errors.Is(err, ErrDecode)) {
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NodeSpec 导出的节点，In和Out是Go的类型名，没有时为空
+type NodeSpec struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	In   string `json:"in,omitempty"`
+	Out  string `json:"out,omitempty"`
+}
+
+// EdgeSpec 导出的边
+type EdgeSpec struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Spec 图的拓扑，不包含节点的处理函数，用于code review和事故复盘时查看
+type Spec struct {
+	Name  string     `json:"name"`
+	Nodes []NodeSpec `json:"nodes"`
+	Edges []EdgeSpec `json:"edges"`
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// Spec 按添加的顺序导出节点和边
+func (g *Graph) Spec() Spec {
+	s := Spec{Name: g.name, Nodes: []NodeSpec{}, Edges: []EdgeSpec{}}
+	for _, n := range g.nodes {
+		s.Nodes = append(s.Nodes, NodeSpec{Name: n.name, Kind: n.kind.String(), In: typeName(n.in), Out: typeName(n.out)})
+	}
+	for _, e := range g.edges {
+		s.Edges = append(s.Edges, EdgeSpec{From: e.from, To: e.to})
+	}
+	return s
+}
+
+// MarshalJSON 导出成JSON，内容和Spec一样
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.Spec())
+}
+
+// shapes 不同种类的节点在DOT中的形状
+var shapes = map[Kind]string{
+	SourceKind:  "invhouse",
+	MapKind:     "box",
+	FlatMapKind: "box3d",
+	SinkKind:    "house",
+}
+
+// DOT 导出成Graphviz的DOT格式，可以用dot -Tsvg渲染。边上标注流过的元素类型
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", g.name)
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range g.nodes {
+		fmt.Fprintf(&b, "  %q [shape=%s, label=%q];\n", n.name, shapes[n.kind], n.name+"\n"+n.kind.String())
+	}
+	for _, e := range g.edges {
+		label := ""
+		if from, ok := g.index[e.from]; ok {
+			label = typeName(from.out)
+		}
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.from, e.to, label)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
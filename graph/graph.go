@@ -0,0 +1,256 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"concurrenceWay/internal/chans"
+	"concurrenceWay/pipeline"
+)
+
+var (
+	// ErrUnknownNode 边引用了不存在的节点
+	ErrUnknownNode = errors.New("graph: unknown node")
+	// ErrDuplicate 重复的节点名或者边
+	ErrDuplicate = errors.New("graph: duplicate")
+	// ErrCycle 图中有环
+	ErrCycle = errors.New("graph: cycle")
+	// ErrTypeMismatch 边两端的类型不兼容
+	ErrTypeMismatch = errors.New("graph: type mismatch")
+	// ErrDangling 有输出的节点没有下游，或者需要输入的节点没有上游
+	ErrDangling = errors.New("graph: dangling node")
+)
+
+// Kind 节点的种类
+type Kind int
+
+const (
+	// SourceKind 没有输入，产生元素
+	SourceKind Kind = iota
+	// MapKind 每个输入元素对应一个输出元素
+	MapKind
+	// FlatMapKind 每个输入元素对应零个或多个输出元素，相当于bridge
+	FlatMapKind
+	// SinkKind 没有输出，消费元素
+	SinkKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case SourceKind:
+		return "source"
+	case MapKind:
+		return "map"
+	case FlatMapKind:
+		return "flatmap"
+	case SinkKind:
+		return "sink"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+type node struct {
+	name string
+	kind Kind
+	in   reflect.Type
+	out  reflect.Type
+	// 下面按kind只有一个不为nil，元素的类型在添加节点时已经擦除成any
+	source  func(ctx context.Context) <-chan any
+	process func(ctx context.Context, v any) ([]any, error)
+	sink    func(ctx context.Context, v any) error
+}
+
+type edge struct {
+	from, to string
+}
+
+// Graph 由命名节点和边组成的有向无环图。一个节点有多条出边时，每个元素会复制给所有下游(tee)；
+// 有多条入边时，所有上游的元素合并后输入(fanIn)。用Source、Map、FlatMap、Sink添加节点，Connect连接
+type Graph struct {
+	name  string
+	nodes []*node
+	index map[string]*node
+	edges []edge
+	// errs 添加节点和边时发现的错误，在Validate中一起返回
+	errs []error
+}
+
+// New 创建一个空的图，name用在导出的DOT和JSON中
+func New(name string) *Graph {
+	return &Graph{name: name, index: map[string]*node{}}
+}
+
+// Name 图的名字
+func (g *Graph) Name() string { return g.name }
+
+func (g *Graph) add(n *node) {
+	if _, ok := g.index[n.name]; ok {
+		g.errs = append(g.errs, fmt.Errorf("%w node %q", ErrDuplicate, n.name))
+		return
+	}
+	g.nodes = append(g.nodes, n)
+	g.index[n.name] = n
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Source 添加一个源节点，fn返回的通道关闭时这个节点结束。ctx取消时fn应该停止
+func Source[T any](g *Graph, name string, fn func(ctx context.Context) <-chan T) {
+	g.add(&node{name: name, kind: SourceKind, out: typeOf[T](), source: func(ctx context.Context) <-chan any {
+		out := make(chan any)
+		go func() {
+			defer close(out)
+			for v := range fn(ctx) {
+				select {
+				case <-ctx.Done():
+					// 读完fn的输出，让它正常退出
+					continue
+				case out <- v:
+				}
+			}
+		}()
+		return out
+	}})
+}
+
+// Map 添加一个转换节点，stage出错时整个图停止，Run返回这个错误
+func Map[In, Out any](g *Graph, name string, stage pipeline.Stage[In, Out]) {
+	g.add(&node{name: name, kind: MapKind, in: typeOf[In](), out: typeOf[Out](), process: func(ctx context.Context, v any) ([]any, error) {
+		r, err := stage(ctx, chans.Cast[In](v))
+		if err != nil {
+			return nil, err
+		}
+		return []any{r}, nil
+	}})
+}
+
+// FlatMap 添加一个展开节点，fn返回的元素依次发给下游
+func FlatMap[In, Out any](g *Graph, name string, fn func(ctx context.Context, v In) ([]Out, error)) {
+	g.add(&node{name: name, kind: FlatMapKind, in: typeOf[In](), out: typeOf[Out](), process: func(ctx context.Context, v any) ([]any, error) {
+		rs, err := fn(ctx, chans.Cast[In](v))
+		if err != nil {
+			return nil, err
+		}
+		out := make([]any, len(rs))
+		for i, r := range rs {
+			out[i] = r
+		}
+		return out, nil
+	}})
+}
+
+// Sink 添加一个终点节点，fn出错时整个图停止
+func Sink[T any](g *Graph, name string, fn func(ctx context.Context, v T) error) {
+	g.add(&node{name: name, kind: SinkKind, in: typeOf[T](), sink: func(ctx context.Context, v any) error {
+		return fn(ctx, chans.Cast[T](v))
+	}})
+}
+
+// Connect 添加from到to的边，可以一次连接多个下游。节点是否存在、类型是否兼容在Validate中检查
+func (g *Graph) Connect(from string, to ...string) *Graph {
+	for _, t := range to {
+		e := edge{from: from, to: t}
+		for _, old := range g.edges {
+			if old == e {
+				g.errs = append(g.errs, fmt.Errorf("%w edge %s -> %s", ErrDuplicate, from, t))
+				e.from = ""
+				break
+			}
+		}
+		if e.from != "" {
+			g.edges = append(g.edges, e)
+		}
+	}
+	return g
+}
+
+// Validate 检查图是否可以运行：节点名不重复，边的两端存在且类型兼容，没有环，
+// 有输出的节点都有下游，需要输入的节点都有上游。返回所有发现的问题
+func (g *Graph) Validate() error {
+	errs := append([]error(nil), g.errs...)
+	outs := map[string]int{}
+	ins := map[string]int{}
+	for _, e := range g.edges {
+		from, ok1 := g.index[e.from]
+		to, ok2 := g.index[e.to]
+		if !ok1 {
+			errs = append(errs, fmt.Errorf("%w %q in edge %s -> %s", ErrUnknownNode, e.from, e.from, e.to))
+		}
+		if !ok2 {
+			errs = append(errs, fmt.Errorf("%w %q in edge %s -> %s", ErrUnknownNode, e.to, e.from, e.to))
+		}
+		if !ok1 || !ok2 {
+			continue
+		}
+		outs[e.from]++
+		ins[e.to]++
+		switch {
+		case from.kind == SinkKind:
+			errs = append(errs, fmt.Errorf("%w: sink %q has no output", ErrTypeMismatch, e.from))
+		case to.kind == SourceKind:
+			errs = append(errs, fmt.Errorf("%w: source %q takes no input", ErrTypeMismatch, e.to))
+		case !from.out.AssignableTo(to.in):
+			errs = append(errs, fmt.Errorf("%w: %s -> %s: %v is not assignable to %v", ErrTypeMismatch, e.from, e.to, from.out, to.in))
+		}
+	}
+	for _, n := range g.nodes {
+		if n.kind != SinkKind && outs[n.name] == 0 {
+			errs = append(errs, fmt.Errorf("%w: output of %q is not connected", ErrDangling, n.name))
+		}
+		if n.kind != SourceKind && ins[n.name] == 0 {
+			errs = append(errs, fmt.Errorf("%w: input of %q is not connected", ErrDangling, n.name))
+		}
+	}
+	if cycle := g.findCycle(); cycle != nil {
+		errs = append(errs, fmt.Errorf("%w: %v", ErrCycle, cycle))
+	}
+	return errors.Join(errs...)
+}
+
+// findCycle 深度优先搜索，返回找到的第一个环经过的节点
+func (g *Graph) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+		for _, e := range g.edges {
+			if e.from != name {
+				continue
+			}
+			switch state[e.to] {
+			case visiting:
+				for i, p := range path {
+					if p == e.to {
+						return append(append([]string(nil), path[i:]...), e.to)
+					}
+				}
+			case unvisited:
+				if c := visit(e.to); c != nil {
+					return c
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, n := range g.nodes {
+		if state[n.name] == unvisited {
+			if c := visit(n.name); c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"concurrenceWay/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func numbers(n int) func(ctx context.Context) <-chan int {
+	return func(ctx context.Context) <-chan int { return source.Range(ctx, 1, n+1, 1) }
+}
+
+// diamond gen分成double和square两路，再合并到format，最后展开成字符输出
+func diamond(collected *[]string, mu *sync.Mutex) *Graph {
+	g := New("diamond")
+	Source(g, "gen", numbers(3))
+	Map(g, "double", func(ctx context.Context, v int) (int, error) { return v * 2, nil })
+	Map(g, "square", func(ctx context.Context, v int) (int, error) { return v * v, nil })
+	Map(g, "format", func(ctx context.Context, v int) (string, error) { return strconv.Itoa(v), nil })
+	FlatMap(g, "chars", func(ctx context.Context, s string) ([]string, error) { return strings.Split(s, ""), nil })
+	Sink(g, "collect", func(ctx context.Context, s string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		*collected = append(*collected, s)
+		return nil
+	})
+	g.Connect("gen", "double", "square").
+		Connect("double", "format").
+		Connect("square", "format").
+		Connect("format", "chars").
+		Connect("chars", "collect")
+	return g
+}
+
+// TestRunDiamondCase 分流再合并，每个元素复制给所有下游
+func TestRunDiamondCase(t *testing.T) {
+	var got []string
+	var mu sync.Mutex
+	g := diamond(&got, &mu)
+	assert.NoError(t, g.Run(context.Background()))
+	// double: 2 4 6，square: 1 4 9
+	assert.ElementsMatch(t, []string{"2", "4", "6", "1", "4", "9"}, got)
+}
+
+// TestRunErrorCase 节点出错时整个图停止，返回这个错误
+func TestRunErrorCase(t *testing.T) {
+	boom := errors.New("boom")
+	g := New("failing")
+	Source(g, "gen", numbers(1000))
+	Map(g, "check", func(ctx context.Context, v int) (int, error) {
+		if v == 10 {
+			return 0, boom
+		}
+		return v, nil
+	})
+	Sink(g, "discard", func(ctx context.Context, v int) error { return nil })
+	g.Connect("gen", "check").Connect("check", "discard")
+	assert.Equal(t, boom, g.Run(context.Background()))
+}
+
+// TestValidateCase 一次返回所有问题
+func TestValidateCase(t *testing.T) {
+	g := New("broken")
+	Source(g, "gen", numbers(1))
+	Source(g, "gen", numbers(1))
+	Map(g, "a", func(ctx context.Context, v int) (int, error) { return v, nil })
+	Map(g, "b", func(ctx context.Context, v int) (int, error) { return v, nil })
+	Sink(g, "text", func(ctx context.Context, s string) error { return nil })
+	Map(g, "unused", func(ctx context.Context, v int) (int, error) { return v, nil })
+	g.Connect("gen", "a").Connect("a", "b").Connect("b", "a", "text").Connect("a", "missing").Connect("gen", "a")
+
+	err := g.Validate()
+	fmt.Println(err)
+	for _, target := range []error{ErrDuplicate, ErrCycle, ErrTypeMismatch, ErrDangling, ErrUnknownNode} {
+		assert.True(t, errors.Is(err, target), target.Error())
+	}
+	assert.Contains(t, err.Error(), "[a b a]")
+	assert.Contains(t, err.Error(), "int is not assignable to string")
+	assert.Contains(t, err.Error(), `input of "unused" is not connected`)
+	assert.Error(t, g.Run(context.Background()))
+
+	var got []string
+	var mu sync.Mutex
+	assert.NoError(t, diamond(&got, &mu).Validate())
+}
+
+// TestInterfaceTypeCase 输出类型可以赋值给接口类型的输入
+func TestInterfaceTypeCase(t *testing.T) {
+	g := New("iface")
+	Source(g, "gen", numbers(2))
+	var got []fmt.Stringer
+	Map(g, "wrap", func(ctx context.Context, v int) (stringer, error) { return stringer(v), nil })
+	Sink(g, "print", func(ctx context.Context, s fmt.Stringer) error {
+		got = append(got, s)
+		return nil
+	})
+	g.Connect("gen", "wrap").Connect("wrap", "print")
+	assert.NoError(t, g.Run(context.Background()))
+	assert.Equal(t, "#1", got[0].String())
+}
+
+type stringer int
+
+func (s stringer) String() string { return "#" + strconv.Itoa(int(s)) }
+
+// TestExportCase DOT和JSON按添加的顺序导出
+func TestExportCase(t *testing.T) {
+	var got []string
+	var mu sync.Mutex
+	g := diamond(&got, &mu)
+	dot := g.DOT()
+	fmt.Println(dot)
+	assert.True(t, strings.HasPrefix(dot, "digraph \"diamond\" {\n  rankdir=LR;\n"))
+	assert.Contains(t, dot, `"gen" [shape=invhouse, label="gen\nsource"];`)
+	assert.Contains(t, dot, `"gen" -> "square" [label="int"];`)
+	assert.Contains(t, dot, `"format" -> "chars" [label="string"];`)
+
+	data, err := json.Marshal(g)
+	assert.NoError(t, err)
+	var spec Spec
+	assert.NoError(t, json.Unmarshal(data, &spec))
+	assert.Equal(t, g.Spec(), spec)
+	assert.Equal(t, NodeSpec{Name: "format", Kind: "map", In: "int", Out: "string"}, spec.Nodes[3])
+	assert.Equal(t, EdgeSpec{From: "square", To: "format"}, spec.Edges[3])
+}
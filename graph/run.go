@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// Run 校验之后运行整个图，每个节点一个goroutine，每条边一个通道。所有源节点结束并且元素都流到终点之后返回nil。
+// 任何节点出错时取消其余节点，返回第一个错误；ctx取消时返回ctx的错误
+func (g *Graph) Run(ctx context.Context) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var first error
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+
+	inputs := map[string][]chan any{}
+	outputs := map[string][]chan any{}
+	for _, e := range g.edges {
+		ch := make(chan any)
+		outputs[e.from] = append(outputs[e.from], ch)
+		inputs[e.to] = append(inputs[e.to], ch)
+	}
+
+	var wg sync.WaitGroup
+	for _, n := range g.nodes {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+			outs := outputs[n.name]
+			defer func() {
+				for _, ch := range outs {
+					close(ch)
+				}
+			}()
+			if n.kind == SourceKind {
+				for v := range n.source(ctx) {
+					if !broadcast(ctx, outs, v) {
+						return
+					}
+				}
+				return
+			}
+			for v := range fanIn(ctx, inputs[n.name]) {
+				if n.kind == SinkKind {
+					if err := n.sink(ctx, v); err != nil {
+						fail(err)
+						return
+					}
+					continue
+				}
+				rs, err := n.process(ctx, v)
+				if err != nil {
+					fail(err)
+					return
+				}
+				for _, r := range rs {
+					if !broadcast(ctx, outs, r) {
+						return
+					}
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+
+// broadcast 把v依次发给所有下游，即tee。ctx取消时返回false
+func broadcast(ctx context.Context, outs []chan any, v any) bool {
+	for _, ch := range outs {
+		select {
+		case <-ctx.Done():
+			return false
+		case ch <- v:
+		}
+	}
+	return true
+}
+
+// fanIn 合并多条入边，只有一条时直接返回
+func fanIn(ctx context.Context, ins []chan any) <-chan any {
+	if len(ins) == 1 {
+		return ins[0]
+	}
+	out := make(chan any)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		go func(in <-chan any) {
+			defer wg.Done()
+			for v := range in {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- v:
+				}
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,96 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+
+	"concurrenceWay/clock"
+)
+
+// Work 一个工作单元，每次调用产出一个结果，返回false表示工作已经全部完成
+type Work[T any] func(ctx context.Context) (T, bool)
+
+// Option Pulse和Steward的可选项
+type Option func(*options)
+
+type options struct {
+	clock   clock.Clock
+	perUnit bool
+	onStart func(n int)
+}
+
+// WithClock 指定时钟，测试时传入clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+// PerUnit 每处理完一个工作单元就发送一次心跳，可以和按间隔发送的心跳同时使用
+func PerUnit() Option {
+	return func(o *options) { o.perUnit = true }
+}
+
+// OnStart Steward每次(重新)启动ward时回调，n从0开始计数，0表示第一次启动
+func OnStart(fn func(n int)) Option {
+	return func(o *options) { o.onStart = fn }
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.clock = clock.OrReal(o.clock)
+	return o
+}
+
+// Pulse 心跳模式。在同一个goroutine里执行work并发送心跳，所以work卡住时心跳也会停下来，监控方据此判断goroutine是否健康
+// interval大于0时每隔interval发送一次心跳，使用PerUnit时每个工作单元结束后再发送一次心跳。
+// 心跳是非阻塞发送的，没人消费时直接丢弃，不会因为没人监听心跳而阻塞工作
+func Pulse[T any](ctx context.Context, interval time.Duration, work Work[T], opts ...Option) (<-chan time.Time, <-chan T) {
+	o := newOptions(opts)
+	heartbeats := make(chan time.Time, 1)
+	results := make(chan T)
+	go func() {
+		defer close(heartbeats)
+		defer close(results)
+
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := o.clock.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C()
+		}
+		sendPulse := func() {
+			select {
+			case heartbeats <- o.clock.Now():
+			default:
+			}
+		}
+
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			v, ok := work(ctx)
+			if !ok {
+				return
+			}
+			if o.perUnit {
+				sendPulse()
+			}
+		send:
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-tick:
+					// 结果没人取的时候也要继续跳动，否则会被误判为卡死
+					sendPulse()
+				case results <- v:
+					break send
+				}
+			}
+		}
+	}()
+	return heartbeats, results
+}
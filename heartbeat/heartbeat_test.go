@@ -0,0 +1,109 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPulsePerUnitCase 每处理完一个工作单元发送一次心跳
+func TestPulsePerUnitCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	i := 0
+	work := func(ctx context.Context) (int, bool) {
+		i++
+		return i, i <= 3
+	}
+	heartbeats, results := Pulse(ctx, 0, work, PerUnit())
+
+	var got []int
+	for {
+		select {
+		case beat := <-heartbeats:
+			fmt.Printf("pulse at %v\n", beat)
+		case r, ok := <-results:
+			if !ok {
+				assert.Equal(t, []int{1, 2, 3}, got)
+				return
+			}
+			got = append(got, r)
+		}
+	}
+}
+
+// TestPulseIntervalCase 结果没人消费的时候，也要按间隔继续发送心跳，否则会被误判为卡死
+func TestPulseIntervalCase(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	work := func(ctx context.Context) (string, bool) { return "done", true }
+	heartbeats, results := Pulse(ctx, time.Second, work, WithClock(fake))
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	beat := <-heartbeats
+	assert.Equal(t, fake.Now(), beat)
+	assert.Equal(t, "done", <-results)
+}
+
+// TestStewardRestartCase ward卡住不跳动时，steward会在missed个间隔后重启它
+func TestStewardRestartCase(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 一个从来不跳动的ward，只会等着被取消
+	stalled := func(ctx context.Context, interval time.Duration) <-chan time.Time {
+		heartbeat := make(chan time.Time)
+		go func() {
+			defer close(heartbeat)
+			<-ctx.Done()
+			fmt.Println("ward: 被取消了")
+		}()
+		return heartbeat
+	}
+	starts := make(chan int, 10)
+	steward := Steward(2, stalled, WithClock(fake), OnStart(func(n int) { starts <- n }))
+	steward(ctx, time.Second)
+	assert.Equal(t, 0, <-starts)
+
+	// steward的ticker和超时timer
+	fake.BlockUntil(2)
+	fake.Advance(time.Second)
+	select {
+	case n := <-starts:
+		t.Fatalf("ward restarted too early: %d", n)
+	default:
+	}
+	fake.Advance(time.Second)
+	assert.Equal(t, 1, <-starts)
+}
+
+// TestStewardHealthyCase ward正常工作结束时，steward也随之退出
+func TestStewardHealthyCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	healthy := func(ctx context.Context, interval time.Duration) <-chan time.Time {
+		i := 0
+		heartbeat, results := Pulse(ctx, interval, func(ctx context.Context) (int, bool) {
+			i++
+			return i, i <= 5
+		}, PerUnit())
+		go func() {
+			for r := range results {
+				fmt.Printf("ward result: %d\n", r)
+			}
+		}()
+		return heartbeat
+	}
+	restarts := 0
+	heartbeat := Steward(3, healthy, OnStart(func(n int) { restarts = n }))(ctx, 10*time.Millisecond)
+	for range heartbeat {
+	}
+	assert.Equal(t, 0, restarts)
+}
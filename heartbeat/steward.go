@@ -0,0 +1,77 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+)
+
+// Ward 被监管的goroutine，启动后按interval发送心跳，ctx取消时必须退出并关闭心跳通道
+type Ward func(ctx context.Context, interval time.Duration) <-chan time.Time
+
+// Steward 监管者。ward连续missed个interval都没有心跳时，认为它已经卡死，取消它的ctx并重新启动一个新的ward
+// Steward返回的也是一个Ward，它自己也按interval发送心跳，所以可以再交给上一级的Steward监管，组成监管树。
+// ward主动关闭心跳通道表示工作正常结束，此时steward也随之退出
+func Steward(missed int, ward Ward, opts ...Option) Ward {
+	if missed < 1 {
+		missed = 1
+	}
+	o := newOptions(opts)
+	return func(ctx context.Context, interval time.Duration) <-chan time.Time {
+		heartbeats := make(chan time.Time, 1)
+		go func() {
+			defer close(heartbeats)
+
+			var wardCancel context.CancelFunc
+			var wardBeat <-chan time.Time
+			starts := 0
+			startWard := func() {
+				var wardCtx context.Context
+				wardCtx, wardCancel = context.WithCancel(ctx)
+				wardBeat = ward(wardCtx, interval)
+				if o.onStart != nil {
+					o.onStart(starts)
+				}
+				starts++
+			}
+			startWard()
+			defer func() { wardCancel() }()
+
+			pulse := o.clock.NewTicker(interval)
+			defer pulse.Stop()
+			timeout := o.clock.NewTimer(time.Duration(missed) * interval)
+			defer timeout.Stop()
+			resetTimeout := func() {
+				if !timeout.Stop() {
+					select {
+					case <-timeout.C():
+					default:
+					}
+				}
+				timeout.Reset(time.Duration(missed) * interval)
+			}
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-pulse.C():
+					select {
+					case heartbeats <- o.clock.Now():
+					default:
+					}
+				case _, ok := <-wardBeat:
+					if !ok {
+						return
+					}
+					resetTimeout()
+				case <-timeout.C():
+					// ward没有按时跳动，取消旧的再起一个新的
+					wardCancel()
+					startWard()
+					timeout.Reset(time.Duration(missed) * interval)
+				}
+			}
+		}()
+		return heartbeats
+	}
+}
@@ -0,0 +1,26 @@
+package chans
+
+import "context"
+
+// Send 发送v，ctx取消时放弃发送并返回false
+func Send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case out <- v:
+		return true
+	}
+}
+
+// Drain 读完in，让阻塞在发送上的上游可以退出
+func Drain[T any](in <-chan T) {
+	for range in {
+	}
+}
+
+// Cast 把any转回T。T是接口类型并且v是nil时直接断言会失败，这时返回零值，
+// 用于reflect.Select或者map[string]any这类拿到的是any的地方
+func Cast[T any](v any) T {
+	t, _ := v.(T)
+	return t
+}
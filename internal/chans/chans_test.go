@@ -0,0 +1,30 @@
+package chans
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCastCase 接口类型的nil转换成零值，不会panic
+func TestCastCase(t *testing.T) {
+	assert.Equal(t, 1, Cast[int](1))
+	assert.Nil(t, Cast[any](nil))
+	assert.Nil(t, Cast[fmt.Stringer](nil))
+	assert.Equal(t, "", Cast[string](1))
+}
+
+// TestSendDrainCase ctx取消时Send返回false，Drain读完剩下的元素
+func TestSendDrainCase(t *testing.T) {
+	ch := make(chan int, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.True(t, Send(ctx, ch, 1))
+	cancel()
+	assert.False(t, Send(ctx, ch, 2))
+	close(ch)
+	Drain(ch)
+	_, ok := <-ch
+	assert.False(t, ok)
+}
@@ -0,0 +1,110 @@
+package loop
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/internal/chans"
+)
+
+// Handler 事件源和回调的绑定，由On、OnTicker、OnTimer、Every、After创建
+type Handler interface {
+	source() source
+}
+
+type source struct {
+	ch reflect.Value
+	// fn 处理收到的值，返回错误时整个循环退出
+	fn func(v reflect.Value) error
+	// once 触发一次之后就移除，用于timer
+	once bool
+	// stop 循环退出时释放资源
+	stop func()
+}
+
+type handlerFunc func() source
+
+func (h handlerFunc) source() source { return h() }
+
+// On 把通道ch绑定到fn，ch关闭后自动从select中移除
+func On[T any](ch <-chan T, fn func(T) error) Handler {
+	return handlerFunc(func() source {
+		return source{
+			ch: reflect.ValueOf(ch),
+			fn: func(v reflect.Value) error { return fn(chans.Cast[T](v.Interface())) },
+		}
+	})
+}
+
+// OnTicker 每次tick调用fn，循环退出时停止ticker。ticker永远不会关闭，所以会一直让循环保持运行，直到ctx结束或者回调返回错误
+func OnTicker(t clock.Ticker, fn func(time.Time) error) Handler {
+	return handlerFunc(func() source {
+		s := On(t.C(), fn).source()
+		s.stop = t.Stop
+		return s
+	})
+}
+
+// OnTimer timer触发时调用一次fn，之后移除
+func OnTimer(t clock.Timer, fn func(time.Time) error) Handler {
+	return handlerFunc(func() source {
+		s := On(t.C(), fn).source()
+		s.once = true
+		s.stop = func() { t.Stop() }
+		return s
+	})
+}
+
+// Every 每隔d调用一次fn
+func Every(d time.Duration, fn func(time.Time) error) Handler {
+	return handlerFunc(func() source {
+		return OnTicker(clock.Real().NewTicker(d), fn).source()
+	})
+}
+
+// After d之后调用一次fn
+func After(d time.Duration, fn func(time.Time) error) Handler {
+	return handlerFunc(func() source {
+		return OnTimer(clock.Real().NewTimer(d), fn).source()
+	})
+}
+
+// Run 替代手写的for-select循环。已经关闭的通道和已经触发的timer会自动从select中移除，不会在零值上空转。
+// 所有事件源都移除后返回nil，ctx结束时返回ctx的错误，回调返回错误时返回该错误
+func Run(ctx context.Context, handlers ...Handler) error {
+	sources := make([]source, 0, len(handlers))
+	for _, h := range handlers {
+		s := h.source()
+		if s.stop != nil {
+			defer s.stop()
+		}
+		sources = append(sources, s)
+	}
+
+	// 第0个case固定是ctx.Done()
+	cases := make([]reflect.SelectCase, 0, len(sources)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, s := range sources {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: s.ch})
+	}
+
+	for len(cases) > 1 {
+		chosen, v, ok := reflect.Select(cases)
+		if chosen == 0 {
+			return ctx.Err()
+		}
+		s := sources[chosen-1]
+		if ok {
+			if err := s.fn(v); err != nil {
+				return err
+			}
+		}
+		if !ok || s.once {
+			cases = append(cases[:chosen], cases[chosen+1:]...)
+			sources = append(sources[:chosen-1], sources[chosen:]...)
+		}
+	}
+	return nil
+}
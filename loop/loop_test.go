@@ -0,0 +1,107 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunCase 改写select_test.go里的TestSimpleCase：stringStream关闭后自动移除，不会在零值上空转，也不需要sleep等待
+func TestRunCase(t *testing.T) {
+	stringStream := make(chan string)
+	intStream := make(chan int)
+	go func() {
+		defer close(stringStream)
+		for _, s := range []string{"a", "b", "c"} {
+			stringStream <- s
+		}
+	}()
+	go func() {
+		defer close(intStream)
+		for i := 1; i <= 2; i++ {
+			intStream <- i
+		}
+	}()
+
+	var strs []string
+	var sum int
+	err := Run(context.Background(),
+		On(stringStream, func(s string) error {
+			fmt.Printf("%v ", s)
+			strs = append(strs, s)
+			return nil
+		}),
+		On(intStream, func(i int) error {
+			sum += i
+			return nil
+		}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, strs)
+	assert.Equal(t, 3, sum)
+}
+
+// TestRunTimerCase ticker和timer，timer触发一次之后移除
+func TestRunTimerCase(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := 0
+	fired := false
+	go func() {
+		fake.BlockUntil(2)
+		for i := 0; i < 3; i++ {
+			fake.Advance(time.Second)
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+	}()
+	err := Run(ctx,
+		OnTicker(fake.NewTicker(time.Second), func(time.Time) error { ticks++; return nil }),
+		OnTimer(fake.NewTimer(2*time.Second), func(time.Time) error { fired = true; return nil }),
+	)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, fired)
+	assert.Greater(t, ticks, 0)
+	// 循环退出后ticker被停止了
+	assert.Equal(t, 0, fake.Waiters())
+}
+
+// TestRunErrorCase 回调返回错误时退出
+func TestRunErrorCase(t *testing.T) {
+	errStop := errors.New("stop")
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	var got []int
+	err := Run(context.Background(), On(ch, func(i int) error {
+		got = append(got, i)
+		if i == 2 {
+			return errStop
+		}
+		return nil
+	}), After(time.Hour, func(time.Time) error { return nil }))
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, []int{1, 2}, got)
+}
+
+// TestRunNilInterfaceCase 接口类型的通道收到nil时不会panic
+func TestRunNilInterfaceCase(t *testing.T) {
+	ch := make(chan interface{}, 2)
+	ch <- nil
+	ch <- 1
+	close(ch)
+	var got []interface{}
+	err := Run(context.Background(), On(ch, func(v interface{}) error {
+		got = append(got, v)
+		return nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, 1}, got)
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/internal/chans"
+)
+
+// Default 注册了内置工厂的Registry，opts同NewRegistry：
+//
+//	source: range {start, end, step}、values {values}
+//	stage:  multiply {multiplier}、add {additive}、batch {size}、rateLimit {perSecond, burst}
+//	sink:   print、discard
+func Default(opts ...Option) *Registry {
+	r := NewRegistry(opts...)
+	r.RegisterSource("range", rangeSource, "start", "end", "step")
+	r.RegisterSource("values", valuesSource, "values")
+	r.RegisterStage("multiply", multiplyStage, "multiplier")
+	r.RegisterStage("add", addStage, "additive")
+	r.RegisterStage("batch", batchStage, "size")
+	r.RegisterStage("rateLimit", rateLimitStage(r.Clock()), "perSecond", "burst")
+	r.RegisterSink("print", printSink)
+	r.RegisterSink("discard", discardSink)
+	return r
+}
+
+// rangeSource [start, end)，step默认1
+func rangeSource(p Params) (func(ctx context.Context) <-chan any, error) {
+	start, err := p.Int("start", 0)
+	if err != nil {
+		return nil, err
+	}
+	end, err := p.RequireInt("end")
+	if err != nil {
+		return nil, err
+	}
+	step, err := p.Int("step", 1)
+	if err != nil {
+		return nil, err
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("param %q: must be positive, got %d", "step", step)
+	}
+	return func(ctx context.Context) <-chan any {
+		out := make(chan any)
+		go func() {
+			defer close(out)
+			for i := start; i < end; i += step {
+				if !chans.Send[any](ctx, out, i) {
+					return
+				}
+			}
+		}()
+		return out
+	}, nil
+}
+
+func valuesSource(p Params) (func(ctx context.Context) <-chan any, error) {
+	values, err := p.List("values")
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) <-chan any {
+		out := make(chan any)
+		go func() {
+			defer close(out)
+			for _, v := range values {
+				if !chans.Send[any](ctx, out, v) {
+					return
+				}
+			}
+		}()
+		return out
+	}, nil
+}
+
+// arith 对int和float64做运算，int和float64混合时结果是float64
+func arith(v any, n float64, intOp func(a, b int) int, floatOp func(a, b float64) float64) (any, error) {
+	switch x := v.(type) {
+	case int:
+		if n == float64(int(n)) {
+			return intOp(x, int(n)), nil
+		}
+		return floatOp(float64(x), n), nil
+	case float64:
+		return floatOp(x, n), nil
+	}
+	return nil, fmt.Errorf("want number, got %T", v)
+}
+
+func multiplyStage(p Params) (Stage, error) {
+	m, err := p.Float("multiplier", 1)
+	if err != nil {
+		return Stage{}, err
+	}
+	return Stage{Map: func(ctx context.Context, v any) (any, error) {
+		return arith(v, m, func(a, b int) int { return a * b }, func(a, b float64) float64 { return a * b })
+	}}, nil
+}
+
+func addStage(p Params) (Stage, error) {
+	a, err := p.Float("additive", 0)
+	if err != nil {
+		return Stage{}, err
+	}
+	return Stage{Map: func(ctx context.Context, v any) (any, error) {
+		return arith(v, a, func(a, b int) int { return a + b }, func(a, b float64) float64 { return a + b })
+	}}, nil
+}
+
+// batchStage 每size个元素合成一个[]any，in关闭时输出剩下不满的一批
+func batchStage(p Params) (Stage, error) {
+	size, err := p.RequireInt("size")
+	if err != nil {
+		return Stage{}, err
+	}
+	if size <= 0 {
+		return Stage{}, fmt.Errorf("param %q: must be positive, got %d", "size", size)
+	}
+	return Stage{Stream: func(ctx context.Context, in <-chan any) <-chan any {
+		out := make(chan any)
+		go func() {
+			defer close(out)
+			batch := make([]any, 0, size)
+			for v := range in {
+				batch = append(batch, v)
+				if len(batch) < size {
+					continue
+				}
+				if !chans.Send[any](ctx, out, batch) {
+					chans.Drain(in)
+					return
+				}
+				batch = make([]any, 0, size)
+			}
+			if len(batch) > 0 {
+				chans.Send[any](ctx, out, batch)
+			}
+		}()
+		return out
+	}}, nil
+}
+
+// rateLimitStage 令牌桶，每秒补充perSecond个令牌，最多攒burst个，时间由c决定
+func rateLimitStage(c clock.Clock) StageFactory {
+	return func(p Params) (Stage, error) {
+		return rateLimit(c, p)
+	}
+}
+
+func rateLimit(c clock.Clock, p Params) (Stage, error) {
+	rate, err := p.Float("perSecond", 0)
+	if err != nil {
+		return Stage{}, err
+	}
+	if rate <= 0 {
+		return Stage{}, fmt.Errorf("param %q: must be positive, got %v", "perSecond", rate)
+	}
+	burst, err := p.Int("burst", 1)
+	if err != nil {
+		return Stage{}, err
+	}
+	if burst <= 0 {
+		return Stage{}, fmt.Errorf("param %q: must be positive, got %d", "burst", burst)
+	}
+	return Stage{Stream: func(ctx context.Context, in <-chan any) <-chan any {
+		out := make(chan any)
+		go func() {
+			defer close(out)
+			tokens, last := float64(burst), c.Now()
+			for v := range in {
+				now := c.Now()
+				tokens = math.Min(float64(burst), tokens+now.Sub(last).Seconds()*rate)
+				last = now
+				if tokens < 1 {
+					wait := time.Duration((1 - tokens) / rate * float64(time.Second))
+					timer := c.NewTimer(wait)
+					select {
+					case <-ctx.Done():
+						timer.Stop()
+						chans.Drain(in)
+						return
+					case now = <-timer.C():
+					}
+					tokens = math.Min(float64(burst), tokens+now.Sub(last).Seconds()*rate)
+					last = now
+				}
+				tokens--
+				if !chans.Send[any](ctx, out, v) {
+					chans.Drain(in)
+					return
+				}
+			}
+		}()
+		return out
+	}}, nil
+}
+
+func printSink(p Params) (func(ctx context.Context, v any) error, error) {
+	return func(ctx context.Context, v any) error {
+		_, err := fmt.Fprintln(os.Stdout, v)
+		return err
+	}, nil
+}
+
+func discardSink(p Params) (func(ctx context.Context, v any) error, error) {
+	return func(ctx context.Context, v any) error { return nil }, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 用YAML描述的线性pipeline：一个source，若干个按顺序执行的stage，一个sink。例如
+//
+//	name: arithmetic
+//	source:
+//	  type: range
+//	  params: {start: 1, end: 100}
+//	stages:
+//	  - type: multiply
+//	    params: {multiplier: 2}
+//	    parallelism: 4
+//	    buffer: 16
+//	  - type: batch
+//	    params: {size: 10}
+//	sink:
+//	  type: print
+type Config struct {
+	Name   string `yaml:"name"`
+	Source Step   `yaml:"source"`
+	Stages []Step `yaml:"stages"`
+	Sink   Step   `yaml:"sink"`
+}
+
+// Step 一个source、stage或者sink。Type是在Registry中注册的名字，Params交给对应的工厂解析
+type Step struct {
+	Type string `yaml:"type"`
+	// Name 出错时用来定位，默认和Type一样
+	Name   string `yaml:"name,omitempty"`
+	Params Params `yaml:"params,omitempty"`
+	// Parallelism 同时处理元素的goroutine数，对无状态的stage和sink有效，默认1。大于1时输出顺序不确定，source和有状态的stage设置大于1会被拒绝
+	Parallelism int `yaml:"parallelism,omitempty"`
+	// Buffer 输出通道的缓冲大小，对sink是输入通道的缓冲大小，默认0
+	Buffer int `yaml:"buffer,omitempty"`
+}
+
+func (s Step) label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Type
+}
+
+// Parse 解析YAML，不认识的字段会报错，防止拼错的配置被悄悄忽略
+func Parse(data []byte) (*Config, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Load 读取并解析path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
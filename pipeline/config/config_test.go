@@ -0,0 +1,225 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+const arithmetic = `
+name: arithmetic
+source:
+  type: range
+  params: {start: 1, end: 11}
+  buffer: 4
+stages:
+  - type: multiply
+    params: {multiplier: 2}
+    parallelism: 4
+    buffer: 8
+  - name: plusOne
+    type: add
+    params: {additive: 1}
+  - type: batch
+    params: {size: 3}
+sink:
+  type: collect
+  buffer: 2
+`
+
+// collector 测试用的sink，记录收到的所有元素
+type collector struct {
+	mu  sync.Mutex
+	got []any
+}
+
+func (c *collector) factory(p Params) (func(ctx context.Context, v any) error, error) {
+	return func(ctx context.Context, v any) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.got = append(c.got, v)
+		return nil
+	}, nil
+}
+
+func registry(c *collector) *Registry {
+	r := Default()
+	r.RegisterSink("collect", c.factory)
+	return r
+}
+
+// TestRunCase 解析、构建、运行，parallelism大于1时顺序不确定，所以排序后比较
+func TestRunCase(t *testing.T) {
+	cfg, err := Parse([]byte(arithmetic))
+	assert.NoError(t, err)
+	assert.Equal(t, "plusOne", cfg.Stages[1].label())
+	assert.Equal(t, 4, cfg.Stages[0].Parallelism)
+
+	c := &collector{}
+	p, err := registry(c).Build(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "arithmetic", p.Name())
+	assert.NoError(t, p.Run(context.Background()))
+
+	var values []int
+	for i, b := range c.got {
+		batch := b.([]any)
+		if i < len(c.got)-1 {
+			assert.Len(t, batch, 3)
+		}
+		for _, v := range batch {
+			values = append(values, v.(int))
+		}
+	}
+	sort.Ints(values)
+	assert.Equal(t, []int{3, 5, 7, 9, 11, 13, 15, 17, 19, 21}, values)
+	assert.Len(t, c.got, 4)
+}
+
+// TestValidateCase 一次报告所有问题
+func TestValidateCase(t *testing.T) {
+	cfg, err := Parse([]byte(`
+source:
+  type: range
+  params: {end: 1.5}
+stages:
+  - type: multiply
+    params: {multiplier: two}
+  - type: square
+  - type: batch
+    params: {size: 0}
+  - type: rateLimit
+    params: {perSecond: 10}
+    parallelism: 2
+  - type: add
+    buffer: -1
+sink:
+  type: nowhere
+`))
+	assert.NoError(t, err)
+	err = Default().Validate(cfg)
+	fmt.Println(err)
+	assert.True(t, errors.Is(err, ErrInvalid))
+	for _, want := range []string{
+		`source range: param "end": want integer, got 1.5`,
+		`stage 0 (multiply): param "multiplier": want number, got two`,
+		`stage 1 (square): unknown stage type "square"`,
+		`stage 2 (batch): param "size": must be positive, got 0`,
+		`stage 3 (rateLimit): stage "rateLimit" is stateful and cannot run with parallelism 2`,
+		`stage 4 (add): negative buffer -1`,
+		`sink nowhere: unknown sink type "nowhere"`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+
+	_, err = Default().Build(&Config{})
+	assert.Contains(t, err.Error(), "source: missing")
+	assert.Contains(t, err.Error(), "sink: missing")
+}
+
+// TestParseCase 拼错的字段不会被悄悄忽略
+func TestParseCase(t *testing.T) {
+	_, err := Parse([]byte("source:\n  type: range\n  paralelism: 2\n"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "paralelism")
+}
+
+// TestErrorCase stage出错时停止并返回带位置的错误
+func TestErrorCase(t *testing.T) {
+	cfg, err := Parse([]byte(`
+source:
+  type: values
+  params: {values: [1, 2, x, 4]}
+stages:
+  - type: add
+    params: {additive: 1}
+sink:
+  type: discard
+`))
+	assert.NoError(t, err)
+	p, err := Default().Build(cfg)
+	assert.NoError(t, err)
+	err = p.Run(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stage 0 (add): want number, got string")
+}
+
+// TestRateLimitCase burst个元素立即通过，之后每1/perSecond秒一个
+func TestRateLimitCase(t *testing.T) {
+	cfg := &Config{
+		Source: Step{Type: "range", Params: Params{"end": 6}},
+		Stages: []Step{{Type: "rateLimit", Params: Params{"perSecond": 4, "burst": 2}}},
+		Sink:   Step{Type: "forward"},
+	}
+	fake := clock.NewFake(time.Now())
+	got := make(chan any)
+	r := Default(WithClock(fake))
+	r.RegisterSink("forward", func(p Params) (func(ctx context.Context, v any) error, error) {
+		return func(ctx context.Context, v any) error {
+			got <- v
+			return nil
+		}, nil
+	})
+	p, err := r.Build(cfg)
+	assert.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- p.Run(context.Background()) }()
+
+	assert.Equal(t, 0, <-got)
+	assert.Equal(t, 1, <-got)
+	for i := 2; i < 6; i++ {
+		// 令牌用完，等待250ms
+		fake.BlockUntil(1)
+		fake.Advance(200 * time.Millisecond)
+		assert.Equal(t, 1, fake.Waiters())
+		fake.Advance(50 * time.Millisecond)
+		assert.Equal(t, i, <-got)
+	}
+	assert.NoError(t, <-done)
+}
+
+// TestUnknownParamCase 拼错的参数名会被拒绝，而不是悄悄使用默认值
+func TestUnknownParamCase(t *testing.T) {
+	cfg, err := Parse([]byte(`
+source:
+  type: range
+  params: {end: 3, stop: 5}
+stages:
+  - type: multiply
+    params: {multipler: 3}
+sink:
+  type: discard
+  params: {verbose: true}
+`))
+	assert.NoError(t, err)
+	_, err = Default().Build(cfg)
+	assert.True(t, errors.Is(err, ErrInvalid))
+	for _, want := range []string{
+		`source range: param "stop": unknown`,
+		`stage 0 (multiply): param "multipler": unknown`,
+		`sink discard: param "verbose": unknown`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+// TestCancelCase ctx取消时返回ctx的错误
+func TestCancelCase(t *testing.T) {
+	cfg := &Config{
+		Source: Step{Type: "range", Params: Params{"end": 1 << 30}},
+		Stages: []Step{{Type: "multiply", Params: Params{"multiplier": 3}, Parallelism: 2}},
+		Sink:   Step{Type: "discard"},
+	}
+	p, err := Default().Build(cfg)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, p.Run(ctx))
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"math"
+)
+
+// Params stage的参数，YAML中的数字解析成int或者float64
+type Params map[string]any
+
+// Int 读取整数参数，不存在时返回def
+func (p Params) Int(key string, def int) (int, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case float64:
+		if n == math.Trunc(n) {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("param %q: want integer, got %v", key, v)
+}
+
+// Float 读取数字参数，不存在时返回def
+func (p Params) Float(key string, def float64) (float64, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("param %q: want number, got %v", key, v)
+}
+
+// RequireInt 必须存在的整数参数
+func (p Params) RequireInt(key string) (int, error) {
+	if _, ok := p[key]; !ok {
+		return 0, fmt.Errorf("param %q: required", key)
+	}
+	return p.Int(key, 0)
+}
+
+// List 读取列表参数，不存在时返回nil
+func (p Params) List(key string) ([]any, error) {
+	v, ok := p[key]
+	if !ok {
+		return nil, nil
+	}
+	l, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("param %q: want list, got %v", key, v)
+	}
+	return l, nil
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/pipeline"
+)
+
+// 配置是运行时才知道的，所以元素在stage之间都以any传递，由各个stage自己检查类型
+
+// ErrInvalid 配置不合法，Validate和Build返回的错误都包含它
+var ErrInvalid = errors.New("config: invalid pipeline")
+
+// Stage 工厂根据参数创建的stage，Map和Stream只有一个不为nil
+type Stage struct {
+	// Map 无状态的逐个元素处理，可以设置parallelism
+	Map pipeline.Stage[any, any]
+	// Stream 有状态的处理，例如batch、rateLimit，只能在一个goroutine里运行。in关闭时处理完剩下的元素后关闭输出
+	Stream func(ctx context.Context, in <-chan any) <-chan any
+}
+
+// SourceFactory 根据参数创建source，返回的函数在ctx取消或者数据耗尽时关闭通道
+type SourceFactory func(p Params) (func(ctx context.Context) <-chan any, error)
+
+// StageFactory 根据参数创建stage
+type StageFactory func(p Params) (Stage, error)
+
+// SinkFactory 根据参数创建sink，返回的函数出错时整个pipeline停止
+type SinkFactory func(p Params) (func(ctx context.Context, v any) error, error)
+
+// Option Registry的可选项
+type Option func(*Registry)
+
+// WithClock 指定时钟，rateLimit这类和时间有关的stage使用它，测试时传入clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(r *Registry) { r.clock = c }
+}
+
+// entry 注册的工厂和它接受的参数名
+type entry[F any] struct {
+	factory F
+	keys    []string
+}
+
+// Registry 按名字注册的source、stage和sink工厂，可以并发使用
+type Registry struct {
+	mu      sync.RWMutex
+	clock   clock.Clock
+	sources map[string]entry[SourceFactory]
+	stages  map[string]entry[StageFactory]
+	sinks   map[string]entry[SinkFactory]
+}
+
+// NewRegistry 创建一个空的Registry，内置的工厂见Default
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		sources: map[string]entry[SourceFactory]{},
+		stages:  map[string]entry[StageFactory]{},
+		sinks:   map[string]entry[SinkFactory]{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.clock = clock.OrReal(r.clock)
+	return r
+}
+
+// Clock WithClock指定的时钟，默认clock.Real，自定义的工厂需要时间时应该使用它
+func (r *Registry) Clock() clock.Clock { return r.clock }
+
+// RegisterSource 注册source工厂，同名的会被覆盖。keys是它接受的参数名，配置里出现其他参数时Build报错，防止拼错的参数被悄悄忽略
+func (r *Registry) RegisterSource(name string, f SourceFactory, keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources[name] = entry[SourceFactory]{f, keys}
+}
+
+// RegisterStage 注册stage工厂，同名的会被覆盖，keys同RegisterSource
+func (r *Registry) RegisterStage(name string, f StageFactory, keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stages[name] = entry[StageFactory]{f, keys}
+}
+
+// RegisterSink 注册sink工厂，同名的会被覆盖，keys同RegisterSource
+func (r *Registry) RegisterSink(name string, f SinkFactory, keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sinks[name] = entry[SinkFactory]{f, keys}
+}
+
+// Stages 已经注册的stage名字，按字典序
+func (r *Registry) Stages() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.stages))
+	for name := range r.stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate 检查cfg能否被构建：类型都已注册，参数合法并且没有不认识的参数，parallelism和buffer不为负数，有状态的stage没有设置parallelism。
+// 返回所有发现的问题，适合在启动之前检查配置
+func (r *Registry) Validate(cfg *Config) error {
+	_, err := r.Build(cfg)
+	return err
+}
+
+// Build 根据cfg创建可以运行的Pipeline
+func (r *Registry) Build(cfg *Config) (*Pipeline, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p := &Pipeline{name: cfg.Name}
+	var errs []error
+	invalid := func(where string, err error) {
+		errs = append(errs, fmt.Errorf("%w: %s: %v", ErrInvalid, where, err))
+	}
+	// checkKeys 报告keys之外的参数，按字典序，保证错误信息稳定
+	checkKeys := func(where string, params Params, keys []string) {
+		var unknown []string
+		for key := range params {
+			if !slices.Contains(keys, key) {
+				unknown = append(unknown, key)
+			}
+		}
+		sort.Strings(unknown)
+		for _, key := range unknown {
+			invalid(where, fmt.Errorf("param %q: unknown", key))
+		}
+	}
+	checkSizes := func(where string, s Step) {
+		if s.Parallelism < 0 {
+			invalid(where, fmt.Errorf("negative parallelism %d", s.Parallelism))
+		}
+		if s.Buffer < 0 {
+			invalid(where, fmt.Errorf("negative buffer %d", s.Buffer))
+		}
+	}
+
+	where := "source " + cfg.Source.label()
+	checkSizes(where, cfg.Source)
+	if cfg.Source.Parallelism > 1 {
+		invalid(where, fmt.Errorf("source cannot run with parallelism %d", cfg.Source.Parallelism))
+	}
+	if cfg.Source.Type == "" {
+		invalid("source", errors.New("missing"))
+	} else if e, ok := r.sources[cfg.Source.Type]; !ok {
+		invalid(where, fmt.Errorf("unknown source type %q", cfg.Source.Type))
+	} else if src, err := e.factory(cfg.Source.Params); err != nil {
+		invalid(where, err)
+	} else {
+		checkKeys(where, cfg.Source.Params, e.keys)
+		p.source, p.sourceBuffer = src, cfg.Source.Buffer
+	}
+
+	for i, s := range cfg.Stages {
+		where := fmt.Sprintf("stage %d (%s)", i, s.label())
+		checkSizes(where, s)
+		e, ok := r.stages[s.Type]
+		if !ok {
+			invalid(where, fmt.Errorf("unknown stage type %q", s.Type))
+			continue
+		}
+		stage, err := e.factory(s.Params)
+		if err != nil {
+			invalid(where, err)
+			continue
+		}
+		checkKeys(where, s.Params, e.keys)
+		if stage.Stream != nil && s.Parallelism > 1 {
+			invalid(where, fmt.Errorf("stage %q is stateful and cannot run with parallelism %d", s.Type, s.Parallelism))
+			continue
+		}
+		parallelism := s.Parallelism
+		if parallelism < 1 {
+			parallelism = 1
+		}
+		p.stages = append(p.stages, built{label: where, stage: stage, parallelism: parallelism, buffer: s.Buffer})
+	}
+
+	where = "sink " + cfg.Sink.label()
+	checkSizes(where, cfg.Sink)
+	if cfg.Sink.Type == "" {
+		invalid("sink", errors.New("missing"))
+	} else if e, ok := r.sinks[cfg.Sink.Type]; !ok {
+		invalid(where, fmt.Errorf("unknown sink type %q", cfg.Sink.Type))
+	} else if sink, err := e.factory(cfg.Sink.Params); err != nil {
+		invalid(where, err)
+	} else {
+		checkKeys(where, cfg.Sink.Params, e.keys)
+		p.sink = sink
+		p.sinkParallelism, p.sinkBuffer = cfg.Sink.Parallelism, cfg.Sink.Buffer
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return p, nil
+}
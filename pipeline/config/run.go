@@ -0,0 +1,128 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type built struct {
+	label       string
+	stage       Stage
+	parallelism int
+	buffer      int
+}
+
+// Pipeline 由Registry.Build创建，可以多次Run
+type Pipeline struct {
+	name            string
+	source          func(ctx context.Context) <-chan any
+	sourceBuffer    int
+	stages          []built
+	sink            func(ctx context.Context, v any) error
+	sinkParallelism int
+	sinkBuffer      int
+}
+
+// Name 配置中的name
+func (p *Pipeline) Name() string { return p.name }
+
+// Run 运行pipeline直到source耗尽并且所有元素都到达sink。任何stage或者sink出错时停止，返回第一个错误；ctx取消时返回ctx的错误
+func (p *Pipeline) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var first error
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+
+	ch := resize(ctx, p.source(ctx), p.sourceBuffer)
+	for _, b := range p.stages {
+		if b.stage.Stream != nil {
+			ch = resize(ctx, b.stage.Stream(ctx, ch), b.buffer)
+			continue
+		}
+		ch = mapAny(ctx, ch, b, fail)
+	}
+	ch = resize(ctx, ch, p.sinkBuffer)
+
+	workers := p.sinkParallelism
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range ch {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := p.sink(ctx, v); err != nil {
+					fail(fmt.Errorf("sink: %w", err))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+
+// mapAny 用b.parallelism个goroutine执行Map，出错时调用fail
+func mapAny(ctx context.Context, in <-chan any, b built, fail func(error)) <-chan any {
+	out := make(chan any, b.buffer)
+	var wg sync.WaitGroup
+	for i := 0; i < b.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range in {
+				if ctx.Err() != nil {
+					// 读完上游，让它们正常退出
+					continue
+				}
+				r, err := b.stage.Map(ctx, v)
+				if err != nil {
+					fail(fmt.Errorf("%s: %w", b.label, err))
+					continue
+				}
+				select {
+				case <-ctx.Done():
+				case out <- r:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// resize 给没有缓冲的通道加上size大小的缓冲，size为0时直接返回
+func resize(ctx context.Context, in <-chan any, size int) <-chan any {
+	if size <= 0 {
+		return in
+	}
+	out := make(chan any, size)
+	go func() {
+		defer close(out)
+		for v := range in {
+			select {
+			case <-ctx.Done():
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,190 @@
+package pipeline
+
+import (
+	"context"
+
+	"concurrenceWay/internal/chans"
+)
+
+// Mode Pipeline的执行方式
+type Mode int
+
+const (
+	// ChannelMode 每个stage一个goroutine，stage之间用通道连接，和pipeline_test.go里手写的multiply、add一样。
+	// 适合有IO、各个stage耗时差不多、需要并行的场景
+	ChannelMode Mode = iota
+	// FusedMode 把所有stage合成一个函数，每个元素在一个goroutine里依次经过所有stage，省掉每个stage的goroutine和通道传递。
+	// 适合multiply、add这种CPU密集又很轻的转换。ThenStream追加的有状态stage不能融合，它把pipeline分成几段，每段各自融合
+	FusedMode
+)
+
+// Pipeline 由若干个Stage串起来的流水线，用New创建，用Then追加无状态的stage，用ThenStream追加有状态的stage。两种Mode的输出完全一样：
+// 某个stage出错时，后面的stage不再执行，错误原样传给下游，Value是最终输出类型的零值
+type Pipeline[In, Out any] struct {
+	mode Mode
+	// fused 所有stage合成的函数，有ThenStream追加的stage时为nil
+	fused Stage[In, Out]
+	// head 到最后一个有状态stage为止的部分，输出转成any，因为后面的Then不知道它的类型；没有有状态的stage时为nil
+	head func(ctx context.Context, in <-chan In) <-chan Result[any]
+	// tail head之后的无状态stage合成的函数
+	tail Stage[any, Out]
+	// chain 通道模式下把输入接到最后一个stage的输出
+	chain func(ctx context.Context, in <-chan Result[In]) <-chan Result[Out]
+}
+
+// New 创建一个空的Pipeline，原样输出输入的元素
+func New[T any](mode Mode) *Pipeline[T, T] {
+	return &Pipeline[T, T]{
+		mode:  mode,
+		fused: func(_ context.Context, v T) (T, error) { return v, nil },
+		chain: func(_ context.Context, in <-chan Result[T]) <-chan Result[T] { return in },
+	}
+}
+
+// Then 在p后面追加一个stage，返回新的Pipeline，p本身不变。Go的方法不能有类型参数，所以是函数而不是方法
+func Then[In, Mid, Out any](p *Pipeline[In, Mid], stage Stage[Mid, Out]) *Pipeline[In, Out] {
+	chain := p.chain
+	next := &Pipeline[In, Out]{
+		mode: p.mode,
+		head: p.head,
+		chain: func(ctx context.Context, in <-chan Result[In]) <-chan Result[Out] {
+			return mapResults(ctx, chain(ctx, in), stage)
+		},
+	}
+	if p.head == nil {
+		next.fused = compose(p.fused, stage)
+	} else {
+		next.tail = compose(p.tail, stage)
+	}
+	return next
+}
+
+// ThenStream 在p后面追加一个有状态的stage，例如按窗口聚合、去重、限速，它要看到前后多个元素，不能逐个元素调用。
+// stream在in关闭时处理完剩下的元素后关闭输出，ctx取消时尽快退出；收到上游出错的元素时应该原样输出。
+// FusedMode下它前后的无状态stage各自融合，它本身运行在单独的goroutine里
+func ThenStream[In, Mid, Out any](p *Pipeline[In, Mid], stream func(ctx context.Context, in <-chan Result[Mid]) <-chan Result[Out]) *Pipeline[In, Out] {
+	chain := p.chain
+	return &Pipeline[In, Out]{
+		mode: p.mode,
+		head: func(ctx context.Context, in <-chan In) <-chan Result[any] {
+			return erase(ctx, stream(ctx, p.runFused(ctx, in)))
+		},
+		tail: func(_ context.Context, v any) (Out, error) { return chans.Cast[Out](v), nil },
+		chain: func(ctx context.Context, in <-chan Result[In]) <-chan Result[Out] {
+			return stream(ctx, chain(ctx, in))
+		},
+	}
+}
+
+// compose 先执行first，成功时再执行second
+func compose[In, Mid, Out any](first Stage[In, Mid], second Stage[Mid, Out]) Stage[In, Out] {
+	return func(ctx context.Context, v In) (Out, error) {
+		var zero Out
+		m, err := first(ctx, v)
+		if err != nil {
+			return zero, err
+		}
+		out, err := second(ctx, m)
+		if err != nil {
+			return zero, err
+		}
+		return out, nil
+	}
+}
+
+// Mode 执行方式
+func (p *Pipeline[In, Out]) Mode() Mode { return p.mode }
+
+// Stage 所有stage合成的一个函数，和Mode无关，直接调用时不需要任何goroutine。有ThenStream追加的stage时返回nil
+func (p *Pipeline[In, Out]) Stage() Stage[In, Out] { return p.fused }
+
+// Run 处理in中的每个元素，结果按输入顺序写入返回的通道。ctx取消或者in关闭时退出
+func (p *Pipeline[In, Out]) Run(ctx context.Context, in <-chan In) <-chan Result[Out] {
+	if p.mode == FusedMode {
+		return p.runFused(ctx, in)
+	}
+	return p.chain(ctx, wrap(ctx, in))
+}
+
+// runFused 每段融合成一个函数，段之间是有状态的stage
+func (p *Pipeline[In, Out]) runFused(ctx context.Context, in <-chan In) <-chan Result[Out] {
+	if p.head == nil {
+		return Map(ctx, in, p.fused)
+	}
+	return mapResults(ctx, p.head(ctx, in), p.tail)
+}
+
+// erase 把Result[T]转成Result[any]
+func erase[T any](ctx context.Context, in <-chan Result[T]) <-chan Result[any] {
+	out := make(chan Result[any])
+	go func() {
+		defer close(out)
+		for r := range in {
+			if !chans.Send(ctx, out, Result[any]{Value: r.Value, Err: r.Err}) {
+				chans.Drain(in)
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// wrap 把输入转成Result，这样每个stage都可以把上游的错误原样传下去
+func wrap[T any](ctx context.Context, in <-chan T) <-chan Result[T] {
+	out := make(chan Result[T])
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case out <- Result[T]{Value: v}:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// mapResults 和Map一样，但是输入是Result，上游已经出错的元素不执行stage
+func mapResults[In, Out any](ctx context.Context, in <-chan Result[In], stage Stage[In, Out]) <-chan Result[Out] {
+	out := make(chan Result[Out])
+	go func() {
+		defer close(out)
+		for {
+			var r Result[In]
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case r, ok = <-in:
+				if !ok {
+					return
+				}
+			}
+			var res Result[Out]
+			if r.Err != nil {
+				res.Err = r.Err
+			} else {
+				res.Value, res.Err = stage(ctx, r.Value)
+				if res.Err != nil {
+					var zero Out
+					res.Value = zero
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- res:
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,41 @@
+package pipeline
+
+import (
+	"context"
+)
+
+// Result 流中携带错误的元素。stage处理失败时不中断整个流，而是把错误和元素一起交给下游，由下游决定怎么处理
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Stage 处理单个元素的函数，是pipeline里最小的处理单位。retry、breaker等都以Stage为单位包装
+type Stage[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// Map 对in中的每个元素执行stage，结果按输入顺序写入返回的通道。ctx取消或者in关闭时退出
+func Map[In, Out any](ctx context.Context, in <-chan In, stage Stage[In, Out]) <-chan Result[Out] {
+	out := make(chan Result[Out])
+	go func() {
+		defer close(out)
+		for {
+			var v In
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-in:
+				if !ok {
+					return
+				}
+			}
+			r, err := stage(ctx, v)
+			select {
+			case <-ctx.Done():
+				return
+			case out <- Result[Out]{Value: r, Err: err}:
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,41 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMapCase Map对每个元素执行stage，出错的元素带着错误继续往下游传
+func TestMapCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 4; i++ {
+			in <- i
+		}
+	}()
+	half := func(ctx context.Context, i int) (int, error) {
+		if i%2 != 0 {
+			return 0, errors.New("odd")
+		}
+		return i / 2, nil
+	}
+	var values []int
+	var errs int
+	for r := range Map(ctx, in, half) {
+		if r.Err != nil {
+			fmt.Println(r.Err)
+			errs++
+			continue
+		}
+		values = append(values, r.Value)
+	}
+	assert.Equal(t, []int{1, 2}, values)
+	assert.Equal(t, 2, errs)
+}
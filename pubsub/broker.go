@@ -0,0 +1,176 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"concurrenceWay/channel"
+)
+
+var (
+	// ErrBrokerClosed broker已经关闭
+	ErrBrokerClosed = errors.New("pubsub: broker closed")
+	// ErrInvalidTopic 主题格式不对，发布的主题不能带通配符
+	ErrInvalidTopic = errors.New("pubsub: invalid topic")
+)
+
+// Policy 慢消费者策略，订阅者的缓冲区满了之后怎么处理新消息
+type Policy int
+
+const (
+	// Block 阻塞发布者，直到订阅者消费或者发布的ctx结束
+	Block Policy = iota
+	// DropNewest 丢弃新消息
+	DropNewest
+	// DropOldest 丢弃缓冲区里最旧的消息，放入新消息
+	DropOldest
+	// Disconnect 直接取消这个订阅，关闭它的通道
+	Disconnect
+)
+
+// SubOption 订阅的可选项
+type SubOption func(*options)
+
+type options struct {
+	policy Policy
+}
+
+// WithPolicy 指定慢消费者策略，默认Block
+func WithPolicy(p Policy) SubOption {
+	return func(o *options) { o.policy = p }
+}
+
+// Subscription 订阅的句柄，用来取消订阅和查看丢弃的消息数
+type Subscription[T any] struct {
+	id      uint64
+	pattern string
+	policy  Policy
+	dropped atomic.Int64
+	ch      *channel.SafeChan[T]
+}
+
+// Broker 进程内基于主题的发布订阅。相比tee写死了两个输出，订阅者可以随时加入和离开
+type Broker[T any] struct {
+	mu     sync.RWMutex
+	subs   map[uint64]*Subscription[T]
+	nextID uint64
+	closed bool
+}
+
+// NewBroker 创建一个broker
+func NewBroker[T any]() *Broker[T] {
+	return &Broker[T]{subs: make(map[uint64]*Subscription[T])}
+}
+
+// Subscribe 订阅主题，支持*和>通配符。返回的通道在取消订阅或者broker关闭时关闭，broker已经关闭时返回的通道也是关闭的
+func (b *Broker[T]) Subscribe(pattern string, bufSize int, opts ...SubOption) (*Subscription[T], <-chan T) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &Subscription[T]{pattern: pattern, policy: o.policy, ch: channel.NewSafeChan[T](bufSize)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		s.ch.Close()
+		return s, s.ch.Out()
+	}
+	b.nextID++
+	s.id = b.nextID
+	b.subs[s.id] = s
+	return s, s.ch.Out()
+}
+
+// Pattern 订阅的主题
+func (s *Subscription[T]) Pattern() string { return s.pattern }
+
+// Dropped 因为消费太慢被丢弃的消息数
+func (s *Subscription[T]) Dropped() int64 { return s.dropped.Load() }
+
+// Unsubscribe 取消订阅，关闭订阅者的通道。缓冲区里还没消费的消息仍然可以读完，可以重复调用
+func (b *Broker[T]) Unsubscribe(sub *Subscription[T]) {
+	b.mu.Lock()
+	delete(b.subs, sub.id)
+	b.mu.Unlock()
+	sub.ch.Close()
+}
+
+// Publish 把v发给所有匹配topic的订阅者。Block策略的订阅者缓冲区满时会阻塞，直到ctx结束
+func (b *Broker[T]) Publish(ctx context.Context, topic string, v T) error {
+	if !validTopic(topic) {
+		return fmt.Errorf("%w: %q", ErrInvalidTopic, topic)
+	}
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrBrokerClosed
+	}
+	var targets []*Subscription[T]
+	for _, s := range b.subs {
+		if match(s.pattern, topic) {
+			targets = append(targets, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, s := range targets {
+		if !s.deliver(ctx, v) {
+			if s.policy == Disconnect {
+				b.Unsubscribe(s)
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭broker，取消所有订阅
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := b.subs
+	b.subs = make(map[uint64]*Subscription[T])
+	b.mu.Unlock()
+	for _, s := range subs {
+		s.ch.Close()
+	}
+}
+
+// deliver 按策略投递，返回false表示消息没有投递成功
+func (s *Subscription[T]) deliver(ctx context.Context, v T) bool {
+	err := s.ch.Offer(v)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, channel.ErrClosed):
+		return false
+	}
+
+	switch s.policy {
+	case Block:
+		if s.ch.TrySend(ctx, v) == nil {
+			return true
+		}
+	case DropOldest:
+		// 没有缓冲区就没有最旧的消息可丢，退化成DropNewest
+		for cap(s.ch.Out()) > 0 && errors.Is(err, channel.ErrFull) {
+			select {
+			case <-s.ch.Out():
+				s.dropped.Add(1)
+			default:
+			}
+			// 失败说明被消费者抢先读走又被别的发布者写满了，再试一次
+			if err = s.ch.Offer(v); err == nil {
+				return true
+			}
+		}
+	}
+	s.dropped.Add(1)
+	return false
+}
@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMatchCase 通配符匹配
+func TestMatchCase(t *testing.T) {
+	assert.True(t, match("orders.eu.created", "orders.eu.created"))
+	assert.True(t, match("orders.*.created", "orders.eu.created"))
+	assert.False(t, match("orders.*", "orders.eu.created"))
+	assert.True(t, match("orders.>", "orders.eu.created"))
+	assert.False(t, match("orders.>", "orders"))
+	assert.False(t, match("orders.>.created", "orders.eu.created"))
+}
+
+// TestBrokerCase 按主题和通配符分发，取消订阅后通道关闭
+func TestBrokerCase(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroker[string]()
+	defer b.Close()
+	exact, exactCh := b.Subscribe("orders.eu.created", 10)
+	_, allCh := b.Subscribe("orders.>", 10)
+
+	assert.NoError(t, b.Publish(ctx, "orders.eu.created", "o1"))
+	assert.NoError(t, b.Publish(ctx, "orders.us.created", "o2"))
+	assert.ErrorIs(t, b.Publish(ctx, "orders.*", "o3"), ErrInvalidTopic)
+
+	assert.Equal(t, "o1", <-exactCh)
+	assert.Equal(t, "o1", <-allCh)
+	assert.Equal(t, "o2", <-allCh)
+
+	b.Unsubscribe(exact)
+	b.Unsubscribe(exact)
+	_, ok := <-exactCh
+	assert.False(t, ok)
+	assert.NoError(t, b.Publish(ctx, "orders.eu.created", "o4"))
+	assert.Equal(t, "o4", <-allCh)
+}
+
+// TestSlowConsumerCase 几种慢消费者策略
+func TestSlowConsumerCase(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroker[int]()
+	defer b.Close()
+	newest, newestCh := b.Subscribe("metrics", 2, WithPolicy(DropNewest))
+	oldest, oldestCh := b.Subscribe("metrics", 2, WithPolicy(DropOldest))
+	_, disconnectCh := b.Subscribe("metrics", 2, WithPolicy(Disconnect))
+
+	for i := 1; i <= 4; i++ {
+		assert.NoError(t, b.Publish(ctx, "metrics", i))
+	}
+	assert.Equal(t, int64(2), newest.Dropped())
+	assert.Equal(t, int64(2), oldest.Dropped())
+	assert.Equal(t, []int{1, 2}, drain(newestCh, 2))
+	assert.Equal(t, []int{3, 4}, drain(oldestCh, 2))
+	// 缓冲区里的消息读完之后通道就关闭了
+	assert.Equal(t, []int{1, 2}, drain(disconnectCh, 3))
+}
+
+// TestBlockPolicyCase Block策略下发布者被阻塞，直到ctx超时
+func TestBlockPolicyCase(t *testing.T) {
+	b := NewBroker[int]()
+	defer b.Close()
+	_, ch := b.Subscribe("jobs", 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.Publish(ctx, "jobs", 1), context.DeadlineExceeded)
+
+	go func() { fmt.Println("received", <-ch) }()
+	assert.NoError(t, b.Publish(context.Background(), "jobs", 2))
+}
+
+func drain(ch <-chan int, max int) []int {
+	var got []int
+	for v := range ch {
+		got = append(got, v)
+		if len(got) == max {
+			break
+		}
+	}
+	return got
+}
@@ -0,0 +1,41 @@
+package pubsub
+
+import (
+	"strings"
+)
+
+// 主题用.分隔成多段，例如 orders.eu.created
+// 订阅时 * 匹配任意一段，> 只能出现在最后，匹配剩下的一段或多段，放在中间的>和空的段不会匹配任何主题
+const (
+	sep       = "."
+	anyOne    = "*"
+	anyRemain = ">"
+)
+
+// match 判断主题topic是否匹配订阅的pattern
+func match(pattern, topic string) bool {
+	ps := strings.Split(pattern, sep)
+	ts := strings.Split(topic, sep)
+	for i, p := range ps {
+		if p == anyRemain {
+			return i == len(ps)-1 && len(ts) > i
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if p != anyOne && p != ts[i] {
+			return false
+		}
+	}
+	return len(ps) == len(ts)
+}
+
+// validTopic 发布的主题不能带通配符
+func validTopic(topic string) bool {
+	for _, t := range strings.Split(topic, sep) {
+		if t == "" || t == anyOne || t == anyRemain {
+			return false
+		}
+	}
+	return true
+}
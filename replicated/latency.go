@@ -0,0 +1,60 @@
+package replicated
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"concurrenceWay/clock"
+)
+
+// Latency 记录最近size次成功请求的耗时，用来计算对冲的时机。并发安全，一个后端共用一个Latency
+type Latency struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+	clock   clock.Clock
+}
+
+// NewLatency 创建一个保存最近size个样本的Latency，c为nil时使用真实时钟
+func NewLatency(size int, c clock.Clock) *Latency {
+	if size < 1 {
+		size = 1
+	}
+	return &Latency{samples: make([]time.Duration, size), clock: clock.OrReal(c)}
+}
+
+// Record 记录一次耗时，样本满了之后覆盖最旧的
+func (l *Latency) Record(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples[l.next] = d
+	l.next++
+	if l.next == len(l.samples) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// Percentile 返回p分位(0~1)的耗时，没有样本时ok为false
+func (l *Latency) Percentile(p float64) (d time.Duration, ok bool) {
+	l.mu.Lock()
+	n := l.next
+	if l.full {
+		n = len(l.samples)
+	}
+	sorted := append([]time.Duration(nil), l.samples[:n]...)
+	l.mu.Unlock()
+	if n == 0 {
+		return 0, false
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	switch {
+	case p <= 0:
+		return sorted[0], true
+	case p >= 1:
+		return sorted[n-1], true
+	}
+	return sorted[int(p*float64(n-1)+0.5)], true
+}
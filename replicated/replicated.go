@@ -0,0 +1,107 @@
+package replicated
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoAttempts Race的n小于1
+var ErrNoAttempts = errors.New("replicated: no attempts")
+
+// Attempt 一次请求，必须响应ctx的取消，输掉的请求就是通过ctx取消的
+type Attempt[T any] func(ctx context.Context) (T, error)
+
+type result[T any] struct {
+	value T
+	err   error
+}
+
+// Race 复制请求模式。同时发起n个相同的请求，取最先成功的那个结果，其余请求通过ctx取消。
+// 只有全部请求都失败时才返回错误，错误是所有请求错误的合并
+func Race[T any](ctx context.Context, n int, fn Attempt[T]) (T, error) {
+	var zero T
+	if n < 1 {
+		return zero, ErrNoAttempts
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	// 返回时取消所有还在执行的请求
+	defer cancel()
+
+	// 带缓冲，输掉的请求即使没人接收也能写入后退出，不会泄露goroutine
+	results := make(chan result[T], n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			v, err := fn(ctx)
+			if err != nil {
+				err = fmt.Errorf("attempt %d: %w", i, err)
+			}
+			results <- result[T]{value: v, err: err}
+		}(i)
+	}
+
+	errs := make([]error, 0, n)
+	for len(errs) < n {
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case r := <-results:
+			if r.err == nil {
+				return r.value, nil
+			}
+			errs = append(errs, r.err)
+		}
+	}
+	return zero, errors.Join(errs...)
+}
+
+// Hedge 对冲请求。先发起一次请求，如果它的耗时超过了历史耗时的percentile分位数还没有返回，再发起第二次请求，取先成功的那个。
+// 还没有历史耗时的时候不对冲；第一次请求在对冲之前失败时直接返回它的错误，失败重试交给retry包。
+// 成功请求的耗时会记录到latency里，用来计算下一次的对冲时机
+func Hedge[T any](ctx context.Context, latency *Latency, percentile float64, fn Attempt[T]) (T, error) {
+	var zero T
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan result[T], 2)
+	launch := func(i int) {
+		start := latency.clock.Now()
+		go func() {
+			v, err := fn(ctx)
+			if err != nil {
+				err = fmt.Errorf("attempt %d: %w", i, err)
+			} else {
+				latency.Record(latency.clock.Since(start))
+			}
+			results <- result[T]{value: v, err: err}
+		}()
+	}
+
+	launch(0)
+	launched := 1
+	var hedge <-chan time.Time
+	if delay, ok := latency.Percentile(percentile); ok {
+		timer := latency.clock.NewTimer(delay)
+		defer timer.Stop()
+		hedge = timer.C()
+	}
+
+	var errs []error
+	for len(errs) < launched {
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-hedge:
+			hedge = nil
+			launch(1)
+			launched++
+		case r := <-results:
+			if r.err == nil {
+				return r.value, nil
+			}
+			errs = append(errs, r.err)
+		}
+	}
+	return zero, errors.Join(errs...)
+}
@@ -0,0 +1,107 @@
+package replicated
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRaceCase 同时发起多个请求，取最快的那个，其余的请求会被取消
+func TestRaceCase(t *testing.T) {
+	var id, canceled atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(4)
+	fn := func(ctx context.Context) (int32, error) {
+		defer wg.Done()
+		i := id.Add(1)
+		if i == 1 {
+			return i, nil
+		}
+		<-ctx.Done()
+		canceled.Add(1)
+		return 0, ctx.Err()
+	}
+	v, err := Race(context.Background(), 4, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+	// 输掉的三个请求都是通过ctx取消的
+	wg.Wait()
+	assert.Equal(t, int32(3), canceled.Load())
+}
+
+// TestRaceAllFailedCase 全部失败时才返回错误，错误是所有请求错误的合并
+func TestRaceAllFailedCase(t *testing.T) {
+	errBackend := errors.New("backend down")
+	_, err := Race(context.Background(), 3, func(ctx context.Context) (string, error) {
+		return "", errBackend
+	})
+	fmt.Println(err)
+	assert.ErrorIs(t, err, errBackend)
+	_, err = Race(context.Background(), 0, func(ctx context.Context) (string, error) { return "", nil })
+	assert.ErrorIs(t, err, ErrNoAttempts)
+}
+
+// TestHedgeCase 第一次请求超过了p90的耗时还没返回，发起第二次请求，第二次先返回
+func TestHedgeCase(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	latency := NewLatency(10, fake)
+	for i := 1; i <= 10; i++ {
+		latency.Record(time.Duration(i) * 10 * time.Millisecond)
+	}
+	p90, _ := latency.Percentile(0.9)
+	assert.Equal(t, 90*time.Millisecond, p90)
+
+	var id atomic.Int32
+	firstCanceled := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		if id.Add(1) == 1 {
+			<-ctx.Done()
+			close(firstCanceled)
+			return "", ctx.Err()
+		}
+		return "hedged", nil
+	}
+	go func() {
+		fake.BlockUntil(1)
+		fake.Advance(p90)
+	}()
+	v, err := Hedge(context.Background(), latency, 0.9, fn)
+	assert.NoError(t, err)
+	assert.Equal(t, "hedged", v)
+	<-firstCanceled
+}
+
+// TestHedgeNoSamplesCase 没有历史耗时的时候不对冲，只发起一次请求
+func TestHedgeNoSamplesCase(t *testing.T) {
+	latency := NewLatency(10, nil)
+	var calls atomic.Int32
+	v, err := Hedge(context.Background(), latency, 0.9, func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 42, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+	assert.Equal(t, int32(1), calls.Load())
+	_, ok := latency.Percentile(0.5)
+	assert.True(t, ok)
+}
+
+// TestHedgeFailureCase 第一次请求在对冲之前失败，不会再发起第二次
+func TestHedgeFailureCase(t *testing.T) {
+	latency := NewLatency(10, nil)
+	var calls atomic.Int32
+	boom := errors.New("boom")
+	_, err := Hedge(context.Background(), latency, 0.9, func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 0, boom
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, int32(1), calls.Load())
+}
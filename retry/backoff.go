@@ -0,0 +1,44 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff 计算第attempt次失败(从1开始)之后要等待多久，prev是上一次等待的时间
+type Backoff func(attempt int, prev time.Duration) time.Duration
+
+// Constant 每次都等待固定的时间
+func Constant(d time.Duration) Backoff {
+	return func(int, time.Duration) time.Duration { return d }
+}
+
+// Exponential 指数退避，等待base、2*base、4*base...，最多等待max
+func Exponential(base, max time.Duration) Backoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		d := base
+		for i := 1; i < attempt; i++ {
+			d *= 2
+			if d >= max || d <= 0 {
+				return max
+			}
+		}
+		return min(d, max)
+	}
+}
+
+// DecorrelatedJitter 去相关抖动，在[base, 3*prev]之间随机取值，最多等待max。
+// 相比固定的指数退避，多个客户端同时失败时不会在同一时刻一起重试
+func DecorrelatedJitter(base, max time.Duration) Backoff {
+	return func(_ int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		upper := prev * 3
+		if upper <= base {
+			return min(base, max)
+		}
+		d := base + time.Duration(rand.Int63n(int64(upper-base)))
+		return min(d, max)
+	}
+}
@@ -0,0 +1,81 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/pipeline"
+)
+
+// ErrExhausted 重试次数或者重试时间用完了，最后一次的错误会被一起包装返回
+var ErrExhausted = errors.New("retry: exhausted")
+
+// Policy 重试策略
+type Policy struct {
+	// Backoff 每次失败后的等待时间，nil时不等待立即重试
+	Backoff Backoff
+	// MaxAttempts 最多执行的次数(包括第一次)，0表示不限制
+	MaxAttempts int
+	// MaxElapsed 从第一次执行开始最多花费的时间，下一次等待会超过这个时间时直接放弃，0表示不限制
+	MaxElapsed time.Duration
+	// Retryable 判断错误是否可以重试，nil表示所有错误都重试
+	Retryable func(error) bool
+	// Clock nil时使用真实时钟
+	Clock clock.Clock
+}
+
+// Do 按照策略执行fn，直到成功、遇到不可重试的错误、重试用完或者ctx被取消
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	_, err := DoValue(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// DoValue 和Do一样，但是fn有返回值
+func DoValue[T any](ctx context.Context, p Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	c := clock.OrReal(p.Clock)
+	start := c.Now()
+	var wait time.Duration
+	for attempt := 1; ; attempt++ {
+		v, err := fn(ctx)
+		if err == nil {
+			return v, nil
+		}
+		if p.Retryable != nil && !p.Retryable(err) {
+			return v, err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return v, fmt.Errorf("%w after %d attempts: %w", ErrExhausted, attempt, err)
+		}
+		if p.Backoff != nil {
+			wait = p.Backoff(attempt, wait)
+		}
+		if p.MaxElapsed > 0 && c.Since(start)+wait > p.MaxElapsed {
+			return v, fmt.Errorf("%w after %v: %w", ErrExhausted, c.Since(start), err)
+		}
+		if wait > 0 {
+			timer := c.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return v, fmt.Errorf("%w: %w", ctx.Err(), err)
+			case <-timer.C():
+			}
+		} else if ctx.Err() != nil {
+			return v, fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+	}
+}
+
+// Retry 把stage包装成带重试的stage，配合pipeline.Map使用时，每个元素失败后单独重试，不影响其他元素
+func Retry[In, Out any](stage pipeline.Stage[In, Out], p Policy) pipeline.Stage[In, Out] {
+	return func(ctx context.Context, in In) (Out, error) {
+		return DoValue(ctx, p, func(ctx context.Context) (Out, error) {
+			return stage(ctx, in)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+var errFlaky = errors.New("flaky")
+
+// TestBackoffCase 三种退避策略的等待时间
+func TestBackoffCase(t *testing.T) {
+	assert.Equal(t, time.Second, Constant(time.Second)(5, 0))
+
+	exp := Exponential(100*time.Millisecond, time.Second)
+	assert.Equal(t, 100*time.Millisecond, exp(1, 0))
+	assert.Equal(t, 400*time.Millisecond, exp(3, 0))
+	assert.Equal(t, time.Second, exp(10, 0))
+
+	jitter := DecorrelatedJitter(100*time.Millisecond, time.Second)
+	prev := time.Duration(0)
+	for i := 1; i < 20; i++ {
+		d := jitter(i, prev)
+		assert.GreaterOrEqual(t, d, 100*time.Millisecond)
+		assert.LessOrEqual(t, d, time.Second)
+		prev = d
+	}
+}
+
+// TestDoMaxAttemptsCase 重试次数用完后返回最后一次的错误
+func TestDoMaxAttemptsCase(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 3}, func(ctx context.Context) error {
+		calls++
+		return errFlaky
+	})
+	fmt.Println(err)
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.ErrorIs(t, err, errFlaky)
+	assert.Equal(t, 3, calls)
+}
+
+// TestDoRetryableCase 不可重试的错误直接返回
+func TestDoRetryableCase(t *testing.T) {
+	errFatal := errors.New("fatal")
+	calls := 0
+	p := Policy{Retryable: func(err error) bool { return !errors.Is(err, errFatal) }}
+	err := Do(context.Background(), p, func(ctx context.Context) error {
+		calls++
+		if calls == 2 {
+			return errFatal
+		}
+		return errFlaky
+	})
+	assert.Equal(t, errFatal, err)
+	assert.Equal(t, 2, calls)
+}
+
+// TestDoMaxElapsedCase 下一次等待会超过MaxElapsed时放弃，使用fake时钟不用真的等待
+func TestDoMaxElapsedCase(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	p := Policy{Backoff: Constant(time.Second), MaxElapsed: 3 * time.Second, Clock: fake}
+	calls := 0
+	go func() {
+		for i := 0; i < 3; i++ {
+			fake.BlockUntil(1)
+			fake.Advance(time.Second)
+		}
+	}()
+	err := Do(context.Background(), p, func(ctx context.Context) error {
+		calls++
+		return errFlaky
+	})
+	assert.ErrorIs(t, err, ErrExhausted)
+	assert.Equal(t, 4, calls)
+}
+
+// TestDoCanceledCase 等待重试期间ctx被取消
+func TestDoCanceledCase(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := Do(ctx, Policy{Backoff: Constant(time.Hour)}, func(ctx context.Context) error {
+		return errFlaky
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, errFlaky)
+}
+
+// TestRetryStageCase 在pipeline中，每个元素失败后单独重试
+func TestRetryStageCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	failures := map[int]int{2: 2, 4: 1}
+	flaky := func(ctx context.Context, i int) (int, error) {
+		if failures[i] > 0 {
+			failures[i]--
+			return 0, errFlaky
+		}
+		return i * 2, nil
+	}
+
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 4; i++ {
+			in <- i
+		}
+	}()
+	var got []int
+	for r := range pipeline.Map(ctx, in, Retry(flaky, Policy{MaxAttempts: 3})) {
+		assert.NoError(t, r.Err)
+		got = append(got, r.Value)
+	}
+	assert.Equal(t, []int{2, 4, 6, 8}, got)
+}
@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed 邮箱已经关闭，Serve退出时也会关闭邮箱
+var ErrClosed = errors.New("rpc: mailbox closed")
+
+// Handler 处理请求，ctx在调用方取消、调用超时或者Serve退出时被取消
+type Handler[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Mailbox 服务端的邮箱，调用方通过Call把请求投进来，Serve从这里取请求处理。
+// 每个请求都带着自己的回复通道，不需要在每种消息里手动加；调用ID只用于日志追踪
+type Mailbox[Req, Resp any] struct {
+	requests chan envelope[Req, Resp]
+	nextID   atomic.Uint64
+	done     chan struct{}
+	once     sync.Once
+}
+
+type envelope[Req, Resp any] struct {
+	id      uint64
+	ctx     context.Context
+	body    Req
+	replyTo chan<- reply[Resp]
+}
+
+type reply[Resp any] struct {
+	body Resp
+	err  error
+}
+
+// NewMailbox 创建缓冲区大小为size的邮箱
+func NewMailbox[Req, Resp any](size int) *Mailbox[Req, Resp] {
+	return &Mailbox[Req, Resp]{requests: make(chan envelope[Req, Resp], size), done: make(chan struct{})}
+}
+
+// Close 关闭邮箱，还在排队或者等待回复的调用返回ErrClosed，之后的调用直接返回ErrClosed。可以重复调用
+func (m *Mailbox[Req, Resp]) Close() {
+	m.once.Do(func() { close(m.done) })
+}
+
+type callIDKey struct{}
+
+// CallID 从handler的ctx中取出这次调用的ID，用于日志追踪
+func CallID(ctx context.Context) (uint64, bool) {
+	id, ok := ctx.Value(callIDKey{}).(uint64)
+	return id, ok
+}
+
+// CallOption Call的可选项
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	timeout time.Duration
+}
+
+// Timeout 单次调用的超时时间，包括排队和处理的时间
+func Timeout(d time.Duration) CallOption {
+	return func(o *callOptions) { o.timeout = d }
+}
+
+// Call 发送请求并等待回复。ctx取消或者超时后立即返回，服务端handler的ctx也会随之取消；邮箱关闭时返回ErrClosed
+func Call[Req, Resp any](ctx context.Context, mailbox *Mailbox[Req, Resp], req Req, opts ...CallOption) (Resp, error) {
+	var o callOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var zero Resp
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	id := mailbox.nextID.Add(1)
+	// 带一个缓冲，调用方提前返回时服务端写回复也不会阻塞
+	replyTo := make(chan reply[Resp], 1)
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-mailbox.done:
+		return zero, ErrClosed
+	case mailbox.requests <- envelope[Req, Resp]{id: id, ctx: ctx, body: req, replyTo: replyTo}:
+	}
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case r := <-replyTo:
+		return r.body, r.err
+	case <-mailbox.done:
+		// Serve返回前会等执行中的handler写完回复再关闭邮箱，所以这里还要再看一次
+		select {
+		case r := <-replyTo:
+			return r.body, r.err
+		default:
+			return zero, ErrClosed
+		}
+	}
+}
+
+// ServeOption Serve的可选项
+type ServeOption func(*serveOptions)
+
+type serveOptions struct {
+	concurrency int
+}
+
+// Concurrency 同时执行的handler数量，默认1，也就是按顺序一个一个处理
+func Concurrency(n int) ServeOption {
+	return func(o *serveOptions) { o.concurrency = n }
+}
+
+// Serve 从邮箱中取请求交给handler处理，直到ctx结束或者邮箱关闭。返回前会等待所有执行中的handler退出，然后关闭邮箱，
+// 还在排队的调用返回ErrClosed
+func Serve[Req, Resp any](ctx context.Context, mailbox *Mailbox[Req, Resp], handler Handler[Req, Resp], opts ...ServeOption) error {
+	o := serveOptions{concurrency: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	defer mailbox.Close()
+	defer wg.Wait()
+	slots := make(chan struct{}, o.concurrency)
+	for {
+		// 先拿到执行名额再取请求，名额用完时请求留在邮箱里排队
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-mailbox.done:
+			return ErrClosed
+		case slots <- struct{}{}:
+		}
+		var env envelope[Req, Resp]
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-mailbox.done:
+			return ErrClosed
+		case env = <-mailbox.requests:
+		}
+		// 调用方已经放弃了，不用再处理
+		if env.ctx.Err() != nil {
+			<-slots
+			continue
+		}
+
+		wg.Add(1)
+		go func(env envelope[Req, Resp]) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			hctx, cancel := context.WithCancel(context.WithValue(env.ctx, callIDKey{}, env.id))
+			defer cancel()
+			// Serve退出时也要取消handler
+			stop := context.AfterFunc(ctx, cancel)
+			defer stop()
+
+			body, err := handler(hctx, env.body)
+			env.replyTo <- reply[Resp]{body: body, err: err}
+		}(env)
+	}
+}
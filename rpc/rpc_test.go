@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCallCase 回复自动送回调用方，不需要手动在消息里加回复通道
+func TestCallCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mailbox := NewMailbox[string, string](0)
+	go Serve(ctx, mailbox, func(ctx context.Context, s string) (string, error) {
+		id, ok := CallID(ctx)
+		assert.True(t, ok)
+		assert.NotZero(t, id)
+		return strings.ToUpper(s), nil
+	}, Concurrency(4))
+
+	var wg sync.WaitGroup
+	for _, s := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			resp, err := Call(ctx, mailbox, s)
+			assert.NoError(t, err)
+			assert.Equal(t, strings.ToUpper(s), resp)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// TestCallTimeoutCase 调用超时后handler的ctx也被取消
+func TestCallTimeoutCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mailbox := NewMailbox[int, int](0)
+	handlerCanceled := make(chan error, 1)
+	go Serve(ctx, mailbox, func(ctx context.Context, i int) (int, error) {
+		<-ctx.Done()
+		handlerCanceled <- ctx.Err()
+		return 0, ctx.Err()
+	})
+
+	_, err := Call(ctx, mailbox, 1, Timeout(20*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, <-handlerCanceled, context.DeadlineExceeded)
+}
+
+// TestServeConcurrencyCase 同时执行的handler不超过Concurrency
+func TestServeConcurrencyCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mailbox := NewMailbox[int, int](10)
+	var running, peak atomic.Int32
+	go Serve(ctx, mailbox, func(ctx context.Context, i int) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return i * i, nil
+	}, Concurrency(2))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := Call(ctx, mailbox, i)
+			assert.NoError(t, err)
+			assert.Equal(t, i*i, resp)
+		}(i)
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+}
+
+// TestServeStopCase Serve退出时取消执行中的handler，并等待它们退出
+func TestServeStopCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mailbox := NewMailbox[int, int](0)
+	started := make(chan struct{})
+	served := make(chan error)
+	go func() {
+		served <- Serve(ctx, mailbox, func(ctx context.Context, i int) (int, error) {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+	}()
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := Call(context.Background(), mailbox, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, <-served, context.Canceled)
+}
+
+// TestServeExitCase Serve退出后，排队中的调用和之后的调用都返回ErrClosed，不会永远阻塞
+func TestServeExitCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mailbox := NewMailbox[int, int](4)
+	served := make(chan error)
+	go func() {
+		served <- Serve(ctx, mailbox, func(ctx context.Context, i int) (int, error) {
+			return i, nil
+		})
+	}()
+	resp, err := Call(context.Background(), mailbox, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resp)
+	cancel()
+	assert.ErrorIs(t, <-served, context.Canceled)
+
+	_, err = Call(context.Background(), mailbox, 2)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+// TestMailboxCloseCase Close让还在排队的调用返回ErrClosed，Serve随之退出
+func TestMailboxCloseCase(t *testing.T) {
+	mailbox := NewMailbox[int, int](4)
+	queued := make(chan error)
+	go func() {
+		_, err := Call(context.Background(), mailbox, 1)
+		queued <- err
+	}()
+	for len(mailbox.requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	mailbox.Close()
+	mailbox.Close()
+	assert.ErrorIs(t, <-queued, ErrClosed)
+	assert.ErrorIs(t, Serve(context.Background(), mailbox, func(ctx context.Context, i int) (int, error) {
+		return i, nil
+	}), ErrClosed)
+}
@@ -0,0 +1,182 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 所有的sink都接收一个创建上游的函数produce，用派生的ctx调用它，读到通道关闭或者ctx取消为止。
+// sink返回时(包括First、Any、出错等提前结束的情况)取消这个ctx，无限的上游也会随之退出，调用者不需要再手动取消。
+// produce创建的goroutine必须在ctx取消时停止发送
+
+// ErrEmpty First和Last在in关闭之前没有收到任何元素
+var ErrEmpty = errors.New("sink: empty stream")
+
+// Option ForEach的可选项
+type Option func(*options)
+
+type options struct {
+	concurrency int
+}
+
+// Concurrency ForEach同时执行fn的goroutine数量，默认1，即按顺序执行
+func Concurrency(n int) Option {
+	return func(o *options) { o.concurrency = n }
+}
+
+// open 用派生的ctx调用produce，sink返回时调用cancel
+func open[T any](ctx context.Context, produce func(context.Context) <-chan T) (context.Context, <-chan T, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, produce(ctx), cancel
+}
+
+// next 从in中读一个元素，ctx取消时返回ctx的错误
+func next[T any](ctx context.Context, in <-chan T) (v T, ok bool, err error) {
+	select {
+	case <-ctx.Done():
+		return v, false, ctx.Err()
+	case v, ok = <-in:
+		if !ok {
+			// ctx取消之后上游可能先关闭in，这种情况也要返回ctx的错误
+			return v, false, ctx.Err()
+		}
+		return v, true, nil
+	}
+}
+
+// Collect 把所有元素按顺序收集到切片里。ctx取消时返回已经收集到的元素和ctx的错误
+func Collect[T any](ctx context.Context, produce func(context.Context) <-chan T) ([]T, error) {
+	ctx, in, cancel := open(ctx, produce)
+	defer cancel()
+	var got []T
+	for {
+		v, ok, err := next(ctx, in)
+		if err != nil || !ok {
+			return got, err
+		}
+		got = append(got, v)
+	}
+}
+
+// Reduce 从init开始依次用fn合并每个元素
+func Reduce[T, A any](ctx context.Context, produce func(context.Context) <-chan T, init A, fn func(A, T) A) (A, error) {
+	ctx, in, cancel := open(ctx, produce)
+	defer cancel()
+	acc := init
+	for {
+		v, ok, err := next(ctx, in)
+		if err != nil || !ok {
+			return acc, err
+		}
+		acc = fn(acc, v)
+	}
+}
+
+// Count 元素的个数
+func Count[T any](ctx context.Context, produce func(context.Context) <-chan T) (int, error) {
+	return Reduce(ctx, produce, 0, func(n int, _ T) int { return n + 1 })
+}
+
+// First 返回第一个元素，之后取消上游。上游为空时返回ErrEmpty
+func First[T any](ctx context.Context, produce func(context.Context) <-chan T) (T, error) {
+	ctx, in, cancel := open(ctx, produce)
+	defer cancel()
+	v, ok, err := next(ctx, in)
+	if err != nil {
+		return v, err
+	}
+	if !ok {
+		return v, ErrEmpty
+	}
+	return v, nil
+}
+
+// Last 返回最后一个元素，上游为空时返回ErrEmpty
+func Last[T any](ctx context.Context, produce func(context.Context) <-chan T) (T, error) {
+	ctx, in, cancel := open(ctx, produce)
+	defer cancel()
+	var last T
+	seen := false
+	for {
+		v, ok, err := next(ctx, in)
+		if err != nil {
+			return last, err
+		}
+		if !ok {
+			if !seen {
+				return last, ErrEmpty
+			}
+			return last, nil
+		}
+		last, seen = v, true
+	}
+}
+
+// Any 是否有元素满足pred，找到第一个之后立即返回
+func Any[T any](ctx context.Context, produce func(context.Context) <-chan T, pred func(T) bool) (bool, error) {
+	ctx, in, cancel := open(ctx, produce)
+	defer cancel()
+	for {
+		v, ok, err := next(ctx, in)
+		if err != nil || !ok {
+			return false, err
+		}
+		if pred(v) {
+			return true, nil
+		}
+	}
+}
+
+// All 是否所有元素都满足pred，上游为空时返回true
+func All[T any](ctx context.Context, produce func(context.Context) <-chan T, pred func(T) bool) (bool, error) {
+	found, err := Any(ctx, produce, func(v T) bool { return !pred(v) })
+	return !found && err == nil, err
+}
+
+// ForEach 对每个元素执行fn。第一个错误出现后取消传给fn的ctx，等已经开始的fn返回之后返回这个错误。
+// 默认按顺序执行，Concurrency(n)时最多n个fn同时执行，执行顺序不确定
+func ForEach[T any](ctx context.Context, produce func(context.Context) <-chan T, fn func(context.Context, T) error, opts ...Option) error {
+	o := options{concurrency: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+	ctx, in, cancel := open(ctx, produce)
+	defer cancel()
+
+	var once sync.Once
+	var first error
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v, ok, err := next(ctx, in)
+				if err != nil || !ok {
+					return
+				}
+				if err := fn(ctx, v); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	// 父ctx被取消
+	return ctx.Err()
+}
@@ -0,0 +1,180 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"concurrenceWay/source"
+	"github.com/stretchr/testify/assert"
+)
+
+// producer 无限输出1, 2, 3...直到ctx取消，exited在生产者goroutine退出时关闭，用来检查sink返回之后上游有没有停下来
+func producer() (func(context.Context) <-chan int, <-chan struct{}) {
+	exited := make(chan struct{})
+	return func(ctx context.Context) <-chan int {
+		out := make(chan int)
+		go func() {
+			defer close(exited)
+			defer close(out)
+			for i := 1; ; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- i:
+				}
+			}
+		}()
+		return out
+	}, exited
+}
+
+func numbers(start, end int) func(context.Context) <-chan int {
+	return func(ctx context.Context) <-chan int { return source.Range(ctx, start, end, 1) }
+}
+
+func values[T any](s ...T) func(context.Context) <-chan T {
+	return func(ctx context.Context) <-chan T { return source.Slice(ctx, s) }
+}
+
+func waitExit(t *testing.T, exited <-chan struct{}) {
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("producer still blocked")
+	}
+}
+
+// TestCollectReduceCountCase 读完整个流
+func TestCollectReduceCountCase(t *testing.T) {
+	ctx := context.Background()
+	got, err := Collect(ctx, numbers(0, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, got)
+
+	sum, err := Reduce(ctx, numbers(1, 5), 0, func(acc, v int) int { return acc + v })
+	assert.NoError(t, err)
+	assert.Equal(t, 10, sum)
+
+	n, err := Count(ctx, values("a", "b"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+// TestFirstLastCase 空流返回ErrEmpty，First提前返回之后上游不会阻塞
+func TestFirstLastCase(t *testing.T) {
+	ctx := context.Background()
+	in, exited := producer()
+	v, err := First(ctx, in)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	waitExit(t, exited)
+
+	v, err = Last(ctx, numbers(0, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, 9, v)
+
+	_, err = First(ctx, values[int]())
+	assert.Equal(t, ErrEmpty, err)
+	_, err = Last(ctx, values[int]())
+	assert.Equal(t, ErrEmpty, err)
+}
+
+// TestAnyAllCase 找到结果之后立即返回
+func TestAnyAllCase(t *testing.T) {
+	ctx := context.Background()
+	in, exited := producer()
+	found, err := Any(ctx, in, func(i int) bool { return i == 3 })
+	assert.NoError(t, err)
+	assert.True(t, found)
+	waitExit(t, exited)
+
+	ok, err := All(ctx, values(0, 2, 4, 6, 8), func(i int) bool { return i%2 == 0 })
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	in, exited = producer()
+	ok, err = All(ctx, in, func(i int) bool { return i < 10 })
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	waitExit(t, exited)
+}
+
+// TestCancelCase ctx取消时返回ctx的错误，上游随之停止
+func TestCancelCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in, exited := producer()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	n, err := Count(ctx, in)
+	assert.Equal(t, context.Canceled, err)
+	assert.Greater(t, n, 0)
+	waitExit(t, exited)
+}
+
+// TestForEachCase 并发执行，第一个错误之后取消其余的fn
+func TestForEachCase(t *testing.T) {
+	ctx := context.Background()
+	var sum, running, peak atomic.Int64
+	err := ForEach(ctx, numbers(1, 101), func(ctx context.Context, i int) error {
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		sum.Add(int64(i))
+		return nil
+	}, Concurrency(4))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5050), sum.Load())
+	assert.LessOrEqual(t, peak.Load(), int64(4))
+
+	boom := errors.New("boom")
+	in, exited := producer()
+	var calls atomic.Int64
+	err = ForEach(ctx, in, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		if i == 10 {
+			return boom
+		}
+		return nil
+	}, Concurrency(2))
+	assert.Equal(t, boom, err)
+	assert.GreaterOrEqual(t, calls.Load(), int64(10))
+	waitExit(t, exited)
+}
+
+// TestWriteToCase 按行和按JSON写入
+func TestWriteToCase(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	n, err := WriteTo(ctx, values("a", "b"), &buf, Lines[string]())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "a\nb\n", buf.String())
+
+	type point struct{ X, Y int }
+	buf.Reset()
+	_, err = WriteTo(ctx, values(point{1, 2}), &buf, JSONLines[point]())
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"X\":1,\"Y\":2}\n", buf.String())
+
+	boom := errors.New("boom")
+	in, exited := producer()
+	n, err = WriteTo(ctx, in, &buf, func(_ io.Writer, v int) error {
+		if v == 3 {
+			return boom
+		}
+		return nil
+	})
+	assert.Equal(t, boom, err)
+	assert.Equal(t, 2, n)
+	waitExit(t, exited)
+}
@@ -0,0 +1,43 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Encoder 把一个元素写到w里
+type Encoder[T any] func(w io.Writer, v T) error
+
+// Lines 用fmt.Fprintln写，每个元素一行
+func Lines[T any]() Encoder[T] {
+	return func(w io.Writer, v T) error {
+		_, err := fmt.Fprintln(w, v)
+		return err
+	}
+}
+
+// JSONLines 每个元素编码成一行JSON
+func JSONLines[T any]() Encoder[T] {
+	return func(w io.Writer, v T) error {
+		return json.NewEncoder(w).Encode(v)
+	}
+}
+
+// WriteTo 用enc把每个元素依次写到w里，返回成功写入的元素个数。写入出错时停止并返回错误
+func WriteTo[T any](ctx context.Context, produce func(context.Context) <-chan T, w io.Writer, enc Encoder[T]) (int, error) {
+	ctx, in, cancel := open(ctx, produce)
+	defer cancel()
+	n := 0
+	for {
+		v, ok, err := next(ctx, in)
+		if err != nil || !ok {
+			return n, err
+		}
+		if err := enc(w, v); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
@@ -0,0 +1,58 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	"concurrenceWay/internal/chans"
+	"concurrenceWay/pipeline"
+)
+
+// Lines 按行读取r，输出的行不包含换行符。读取出错时输出一个带错误的结果并结束。
+// 阻塞在Read上时ctx取消不能打断它，需要调用者关闭r
+func Lines(ctx context.Context, r io.Reader) <-chan pipeline.Result[string] {
+	out := make(chan pipeline.Result[string])
+	go func() {
+		defer close(out)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if !chans.Send(ctx, out, pipeline.Result[string]{Value: scanner.Text()}) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			chans.Send(ctx, out, pipeline.Result[string]{Err: err})
+		}
+	}()
+	return out
+}
+
+// Walk 按字典序遍历root下的所有普通文件，输出文件路径。某个目录读取失败时输出一个带错误的结果，跳过这个目录继续遍历
+func Walk(ctx context.Context, root string) <-chan pipeline.Result[string] {
+	out := make(chan pipeline.Result[string])
+	go func() {
+		defer close(out)
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if !chans.Send(ctx, out, pipeline.Result[string]{Value: path, Err: err}) {
+					return ctx.Err()
+				}
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if !chans.Send(ctx, out, pipeline.Result[string]{Value: path}) {
+				return ctx.Err()
+			}
+			return nil
+		})
+	}()
+	return out
+}
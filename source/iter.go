@@ -0,0 +1,36 @@
+package source
+
+import (
+	"context"
+	"iter"
+
+	"concurrenceWay/internal/chans"
+)
+
+// Seq 按顺序输出seq产生的元素。ctx取消时yield返回false，seq应该随之停止
+func Seq[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range seq {
+			if !chans.Send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Seq2 和Seq一样，把seq产生的每一对值作为KV输出
+func Seq2[K, V any](ctx context.Context, seq iter.Seq2[K, V]) <-chan KV[K, V] {
+	out := make(chan KV[K, V])
+	go func() {
+		defer close(out)
+		for k, v := range seq {
+			if !chans.Send(ctx, out, KV[K, V]{Key: k, Value: v}) {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,39 @@
+package source
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSeqCase iter.Seq和iter.Seq2转成通道
+func TestSeqCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.Equal(t, []int{1, 2, 3}, collect(Seq(ctx, slices.Values([]int{1, 2, 3}))))
+
+	got := collect(Seq2(ctx, maps.All(map[string]int{"a": 1})))
+	assert.Equal(t, []KV[string, int]{{Key: "a", Value: 1}}, got)
+}
+
+// TestSeqCancelCase 取消之后yield返回false，无限的seq也会停下来
+func TestSeqCancelCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	naturals := func(yield func(int) bool) {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	out := Seq(ctx, naturals)
+	assert.Equal(t, 0, <-out)
+	assert.Equal(t, 1, <-out)
+	cancel()
+	<-stopped
+}
@@ -0,0 +1,95 @@
+package source
+
+import (
+	"context"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/internal/chans"
+)
+
+// 所有的source都在数据耗尽时关闭返回的通道，ctx取消时停止并关闭。和channel包里的repeat一样，消费者不读的时候source阻塞在发送上
+
+// KV Map和Seq2输出的键值对
+type KV[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// Integer Range支持的整数类型
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Slice 按顺序输出s中的元素
+func Slice[T any](ctx context.Context, s []T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range s {
+			if !chans.Send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Map 输出m中的键值对，和range一样顺序不确定。m在输出期间不能被修改
+func Map[K comparable, V any](ctx context.Context, m map[K]V) <-chan KV[K, V] {
+	out := make(chan KV[K, V])
+	go func() {
+		defer close(out)
+		for k, v := range m {
+			if !chans.Send(ctx, out, KV[K, V]{Key: k, Value: v}) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Range 输出[start, end)之间间隔为step的整数，step为负数时从大到小。step为0时panic
+func Range[T Integer](ctx context.Context, start, end, step T) <-chan T {
+	if step == 0 {
+		panic("source: zero step for Range")
+	}
+	// 无符号类型的负数step会被当成很大的正数，和普通的加法溢出一样由调用者保证
+	down := step < 0
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := start; (!down && i < end) || (down && i > end); i += step {
+			if !chans.Send(ctx, out, i) {
+				return
+			}
+			// 下一个值溢出时已经到了类型的边界，不会再有合法的值
+			if next := i + step; (!down && next < i) || (down && next > i) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Ticker 每隔d输出一次当前时间，直到ctx取消。c为nil时使用真实时钟。和time.Ticker一样，消费者跟不上时丢弃多余的tick
+func Ticker(ctx context.Context, d time.Duration, c clock.Clock) <-chan time.Time {
+	ticker := clock.OrReal(c).NewTicker(d)
+	out := make(chan time.Time)
+	go func() {
+		defer close(out)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-ticker.C():
+				if !chans.Send(ctx, out, t) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,116 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func collect[T any](ch <-chan T) []T {
+	var got []T
+	for v := range ch {
+		got = append(got, v)
+	}
+	return got
+}
+
+// TestSliceCase 耗尽时关闭，取消时停止
+func TestSliceCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Equal(t, []string{"a", "b", "c"}, collect(Slice(ctx, []string{"a", "b", "c"})))
+
+	out := Slice(ctx, []int{1, 2, 3})
+	assert.Equal(t, 1, <-out)
+	cancel()
+	// 取消之后最多还能读到一个已经在select里的元素
+	n := len(collect(out))
+	assert.LessOrEqual(t, n, 1)
+}
+
+// TestMapCase 顺序不确定，排序之后比较
+func TestMapCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var keys []string
+	for kv := range Map(ctx, map[string]int{"a": 1, "b": 2}) {
+		keys = append(keys, kv.Key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "b"}, keys)
+}
+
+// TestRangeCase 正向、反向和边界
+func TestRangeCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.Equal(t, []int{0, 3, 6, 9}, collect(Range(ctx, 0, 10, 3)))
+	assert.Equal(t, []int{5, 4, 3}, collect(Range(ctx, 5, 2, -1)))
+	assert.Empty(t, collect(Range(ctx, 2, 2, 1)))
+	assert.Equal(t, []uint8{250, 253}, collect(Range[uint8](ctx, 250, 255, 3)))
+	assert.Panics(t, func() { Range(ctx, 0, 1, 0) })
+}
+
+// TestTickerCase 用fake时钟推进时间
+func TestTickerCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now()
+	fake := clock.NewFake(start)
+	out := Ticker(ctx, time.Second, fake)
+	fake.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-out)
+	fake.Advance(time.Second)
+	assert.Equal(t, start.Add(2*time.Second), <-out)
+	cancel()
+	for range out {
+	}
+	assert.Equal(t, 0, fake.Waiters())
+}
+
+// TestLinesCase 按行输出，读取出错时输出错误
+func TestLinesCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lines []string
+	for r := range Lines(ctx, strings.NewReader("one\ntwo\r\nthree")) {
+		assert.NoError(t, r.Err)
+		lines = append(lines, r.Value)
+	}
+	assert.Equal(t, []string{"one", "two", "three"}, lines)
+
+	boom := errors.New("boom")
+	got := collect(Lines(ctx, iotest.ErrReader(boom)))
+	assert.Len(t, got, 1)
+	assert.Equal(t, boom, got[0].Err)
+}
+
+// TestWalkCase 只输出普通文件，按字典序
+func TestWalkCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	root := t.TempDir()
+	for _, name := range []string{"b.txt", "a/1.log", "a/2.log"} {
+		path := filepath.Join(root, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0o644))
+	}
+	var files []string
+	for r := range Walk(ctx, root) {
+		assert.NoError(t, r.Err)
+		rel, _ := filepath.Rel(root, r.Value)
+		files = append(files, filepath.ToSlash(rel))
+	}
+	assert.Equal(t, []string{"a/1.log", "a/2.log", "b.txt"}, files)
+
+	got := collect(Walk(ctx, filepath.Join(root, "missing")))
+	assert.Len(t, got, 1)
+	assert.True(t, errors.Is(got[0].Err, os.ErrNotExist))
+}
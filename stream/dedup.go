@@ -0,0 +1,241 @@
+package stream
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/internal/chans"
+)
+
+// DedupStats Dedup的统计，可以在运行中读取
+type DedupStats struct {
+	passed  atomic.Int64
+	dropped atomic.Int64
+}
+
+// Passed 输出的元素个数
+func (s *DedupStats) Passed() int64 { return s.passed.Load() }
+
+// Dropped 被当作重复丢弃的元素个数。近似模式下包括误判的
+func (s *DedupStats) Dropped() int64 { return s.dropped.Load() }
+
+// DedupOption Dedup的可选项
+type DedupOption func(*dedupOptions)
+
+type dedupOptions struct {
+	clock         clock.Clock
+	capacity      int
+	ttl           time.Duration
+	bloomExpected int
+	bloomFP       float64
+}
+
+// WithCapacity 精确模式最多记住的key数，超过时忘掉最久没出现的，默认65536
+func WithCapacity(n int) DedupOption {
+	return func(o *dedupOptions) { o.capacity = n }
+}
+
+// WithTTL 精确模式中key超过d没再出现就被忘掉，默认0表示只受WithCapacity限制
+func WithTTL(d time.Duration) DedupOption {
+	return func(o *dedupOptions) { o.ttl = d }
+}
+
+// WithBloom 切换到近似模式，expected是每个过滤器预计容纳的key数，fpRate是误判率，不大于0时为0.01
+func WithBloom(expected int, fpRate float64) DedupOption {
+	return func(o *dedupOptions) { o.bloomExpected, o.bloomFP = expected, fpRate }
+}
+
+// WithTTLClock WithTTL使用的时钟，测试时传入clock.Fake
+func WithTTLClock(c clock.Clock) DedupOption {
+	return func(o *dedupOptions) { o.clock = c }
+}
+
+func newDedupOptions(opts []DedupOption) dedupOptions {
+	o := dedupOptions{capacity: 1 << 16}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.clock = clock.OrReal(o.clock)
+	if o.capacity < 1 {
+		o.capacity = 1
+	}
+	if o.bloomFP <= 0 || o.bloomFP >= 1 {
+		o.bloomFP = 0.01
+	}
+	return o
+}
+
+// seenSet 判断key是否出现过，并把它记为出现过
+type seenSet interface {
+	seen(key string) bool
+}
+
+// Dedup 去重，keyFn相同的元素只输出第一个。内存有上限：
+// 默认是精确模式，最多记住WithCapacity个最近出现的key(LRU)，WithTTL大于0时超过这个时间没再出现的key会被忘掉；
+// WithBloom切换到近似模式，用布隆过滤器，内存更小，但有按误判率把新元素当成重复丢弃的可能
+func Dedup[T any](ctx context.Context, in <-chan T, keyFn func(T) string, opts ...DedupOption) (<-chan T, *DedupStats) {
+	o := newDedupOptions(opts)
+	var set seenSet
+	if o.bloomExpected > 0 {
+		set = newRotatingBloom(o.bloomExpected, o.bloomFP)
+	} else {
+		set = newLRUSet(o.capacity, o.ttl, o.clock)
+	}
+	stats := &DedupStats{}
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if set.seen(keyFn(v)) {
+					stats.dropped.Add(1)
+					continue
+				}
+				stats.passed.Add(1)
+				if !chans.Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out, stats
+}
+
+// DedupMerge 等权合并inputs之后去重，多个上游重放或者重试产生的相同元素只输出一次
+func DedupMerge[T any](ctx context.Context, inputs []<-chan T, keyFn func(T) string, opts ...DedupOption) (<-chan T, *DedupStats) {
+	weights := make([]int, len(inputs))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return Dedup(ctx, WeightedMerge(ctx, inputs, weights), keyFn, opts...)
+}
+
+type lruEntry struct {
+	key string
+	at  time.Time
+}
+
+// lruSet 精确模式，最近出现的key在链表头部
+type lruSet struct {
+	capacity int
+	ttl      time.Duration
+	clock    clock.Clock
+	order    *list.List
+	keys     map[string]*list.Element
+}
+
+func newLRUSet(capacity int, ttl time.Duration, c clock.Clock) *lruSet {
+	return &lruSet{capacity: capacity, ttl: ttl, clock: c, order: list.New(), keys: map[string]*list.Element{}}
+}
+
+func (s *lruSet) seen(key string) bool {
+	now := s.clock.Now()
+	// 从尾部移除过期的key
+	if s.ttl > 0 {
+		for e := s.order.Back(); e != nil && now.Sub(e.Value.(*lruEntry).at) > s.ttl; e = s.order.Back() {
+			s.remove(e)
+		}
+	}
+	if e, ok := s.keys[key]; ok {
+		e.Value.(*lruEntry).at = now
+		s.order.MoveToFront(e)
+		return true
+	}
+	s.keys[key] = s.order.PushFront(&lruEntry{key: key, at: now})
+	if s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return false
+}
+
+func (s *lruSet) remove(e *list.Element) {
+	s.order.Remove(e)
+	delete(s.keys, e.Value.(*lruEntry).key)
+}
+
+// bloom 布隆过滤器，用两个哈希值组合出k个位置
+type bloom struct {
+	bits  []uint64
+	m     uint64
+	k     int
+	count int
+}
+
+// newBloom 按预计元素个数n和误判率p计算位数和哈希函数个数
+func newBloom(n int, p float64) *bloom {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// add 加入key，有新的位被置上时计数加一
+func (b *bloom) add(h1, h2 uint64) {
+	present := true
+	for i := 0; i < b.k; i++ {
+		pos := (h1 + uint64(i)*h2) % b.m
+		word, bit := pos/64, uint64(1)<<(pos%64)
+		if b.bits[word]&bit == 0 {
+			present = false
+			b.bits[word] |= bit
+		}
+	}
+	if !present {
+		b.count++
+	}
+}
+
+func (b *bloom) has(h1, h2 uint64) bool {
+	for i := 0; i < b.k; i++ {
+		pos := (h1 + uint64(i)*h2) % b.m
+		if b.bits[pos/64]&(uint64(1)<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// rotatingBloom 近似模式。一个过滤器装满预计的元素个数之后误判率会上升，这时把它降为旧的，开始一个新的，
+// 查询时两个都查。内存固定为两个过滤器，最近出现过的至少n个key一定能被识别
+type rotatingBloom struct {
+	n        int
+	p        float64
+	current  *bloom
+	previous *bloom
+}
+
+func newRotatingBloom(n int, p float64) *rotatingBloom {
+	return &rotatingBloom{n: n, p: p, current: newBloom(n, p)}
+}
+
+func (r *rotatingBloom) seen(key string) bool {
+	h1 := hashKey(key)
+	// 第二个哈希值由第一个旋转混合得到，置最低位避免为0，否则k个位置都一样
+	h2 := bits.RotateLeft64(h1, 31)*0x9e3779b97f4a7c15 | 1
+	if r.current.has(h1, h2) {
+		return true
+	}
+	// 在previous里出现过的也加入current，防止下次rotate之后被忘掉
+	dup := r.previous != nil && r.previous.has(h1, h2)
+	r.current.add(h1, h2)
+	if r.current.count >= r.n {
+		r.previous, r.current = r.current, newBloom(r.n, r.p)
+	}
+	return dup
+}
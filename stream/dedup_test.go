@@ -0,0 +1,93 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func identity(s string) string { return s }
+
+// TestDedupCase 重复的元素只输出第一个，并统计丢弃的个数
+func TestDedupCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, stats := Dedup(ctx, buffered("a", "b", "a", "c", "b", "a"), identity)
+	assert.Equal(t, []string{"a", "b", "c"}, collect(out))
+	assert.Equal(t, int64(3), stats.Dropped())
+	assert.Equal(t, int64(3), stats.Passed())
+}
+
+// TestDedupCapacityCase 超过容量时忘掉最久没出现的key
+func TestDedupCapacityCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, _ := Dedup(ctx, buffered("a", "b", "c", "a", "b"), identity, WithCapacity(2))
+	// c进来时a被忘掉，a再进来时b被忘掉
+	assert.Equal(t, []string{"a", "b", "c", "a", "b"}, collect(out))
+
+	out, _ = Dedup(ctx, buffered("a", "b", "a", "c", "a"), identity, WithCapacity(2))
+	// 第二个a刷新了a，c进来时被忘掉的是b
+	assert.Equal(t, []string{"a", "b", "c"}, collect(out))
+}
+
+// TestDedupTTLCase 超过TTL没再出现的key被忘掉
+func TestDedupTTLCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := clock.NewFake(time.Now())
+	in := make(chan string)
+	out, stats := Dedup(ctx, in, identity, WithTTL(time.Minute), WithTTLClock(fake))
+	in <- "a"
+	assert.Equal(t, "a", <-out)
+	in <- "a"
+	in <- "b"
+	assert.Equal(t, "b", <-out)
+	assert.Equal(t, int64(1), stats.Dropped())
+	fake.Advance(2 * time.Minute)
+	in <- "a"
+	assert.Equal(t, "a", <-out)
+	close(in)
+}
+
+// TestDedupBloomCase 近似模式没有漏掉重复，误判率接近配置的值
+func TestDedupBloomCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const n = 10000
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for i := 0; i < n; i++ {
+			in <- strconv.Itoa(i)
+			// 每个key紧接着重复一次
+			in <- strconv.Itoa(i)
+		}
+	}()
+	out, stats := Dedup(ctx, in, identity, WithBloom(n, 0.01))
+	passed := len(collect(out))
+	falsePositives := n - passed
+	fmt.Println("bloom false positives:", falsePositives)
+	assert.Equal(t, int64(2*n), stats.Passed()+stats.Dropped())
+	assert.Less(t, falsePositives, n*3/100)
+}
+
+// TestDedupMergeCase 多路输入重放了相同的元素，合并之后只输出一次
+func TestDedupMergeCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inputs := []<-chan string{
+		buffered("order-1", "order-2", "order-3"),
+		buffered("order-2", "order-4"),
+		buffered("order-1", "order-4", "order-5"),
+	}
+	out, stats := DedupMerge(ctx, inputs, identity)
+	got := collect(out)
+	assert.ElementsMatch(t, []string{"order-1", "order-2", "order-3", "order-4", "order-5"}, got)
+	assert.Equal(t, int64(3), stats.Dropped())
+}
@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"context"
+	"iter"
+
+	"concurrenceWay/source"
+)
+
+// All 把produce创建的通道转成iter.Seq，可以直接for v := range。每次遍历都调用一次produce，
+// 循环结束、提前break或者ctx取消时，传给produce的ctx随之取消，上游退出，不会像break出range take(...)那样泄漏。
+// produce创建的goroutine必须在ctx取消时停止发送
+func All[T any](ctx context.Context, produce func(ctx context.Context) <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		ch := produce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-ch:
+				if !ok || !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// From 把iter.Seq转成通道，seq耗尽时关闭，ctx取消时停止
+func From[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	return source.Seq(ctx, seq)
+}
+
+// TakeSeq 拉取seq的前n个元素。通过iter.Pull按需拉取，不需要额外的goroutine和通道，适合热点路径。
+// 结束时调用stop，seq中的defer会正常执行
+func TakeSeq[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		next, stop := iter.Pull(seq)
+		defer stop()
+		for i := 0; i < n; i++ {
+			v, ok := next()
+			if !ok || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// RepeatSeq 无限重复values，和channel包里的repeat一样，通常和TakeSeq一起使用
+func RepeatSeq[T any](values ...T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if len(values) == 0 {
+			return
+		}
+		for {
+			for _, v := range values {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// RepeatFnSeq 无限重复调用fn
+func RepeatFnSeq[T any](fn func() T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for yield(fn()) {
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package stream
+
+import (
+	"context"
+	"time"
+)
+
+// Pair Zip和CombineLatest的输出
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Zip 逐个配对a和b中的元素，任意一边关闭时结束。不用再像TestTeeChannelSimpleCase那样在循环out1时手动读<-out2
+func Zip[A, B any](ctx context.Context, a <-chan A, b <-chan B) <-chan Pair[A, B] {
+	out := make(chan Pair[A, B])
+	go func() {
+		defer close(out)
+		for {
+			var p Pair[A, B]
+			var ok bool
+			// 两边都要等到，先等哪边无所谓
+			select {
+			case <-ctx.Done():
+				return
+			case p.First, ok = <-a:
+				if !ok {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case p.Second, ok = <-b:
+				if !ok {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- p:
+			}
+		}
+	}()
+	return out
+}
+
+// CombineLatest 任意一边有新元素时，和另一边最新的元素组成一对输出。两边都至少来过一个元素之后才开始输出，两边都关闭时结束
+func CombineLatest[A, B any](ctx context.Context, a <-chan A, b <-chan B) <-chan Pair[A, B] {
+	out := make(chan Pair[A, B])
+	go func() {
+		defer close(out)
+		var latest Pair[A, B]
+		var hasA, hasB bool
+		for a != nil || b != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-a:
+				if !ok {
+					// 置为nil之后select不会再选中它
+					a = nil
+					continue
+				}
+				latest.First, hasA = v, true
+			case v, ok := <-b:
+				if !ok {
+					b = nil
+					continue
+				}
+				latest.Second, hasB = v, true
+			}
+			if !hasA || !hasB {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- latest:
+			}
+		}
+	}()
+	return out
+}
+
+// Unmatched Join中在窗口内没有匹配上的元素的处理方式
+type Unmatched int
+
+const (
+	// DropUnmatched 丢弃，即内连接
+	DropUnmatched Unmatched = iota
+	// EmitUnmatched 过期时单独输出，只有一边有值，即全外连接
+	EmitUnmatched
+)
+
+// Joined Join的输出，内连接时两边都有值
+type Joined[K comparable, L, R any] struct {
+	Key      K
+	Left     L
+	Right    R
+	HasLeft  bool
+	HasRight bool
+}
+
+type pending[T any] struct {
+	value   T
+	at      time.Time
+	matched bool
+}
+
+// Join 按key做时间窗口内的连接。一边来了新元素时，和另一边window时间内到达的相同key的元素逐个配对输出。
+// 两边各自最多缓冲WithMaxBuffered个元素，超过window或者超出缓冲的元素被移除，没匹配上的按WithUnmatched处理。两边都关闭时结束
+func Join[L, R any, K comparable](ctx context.Context, left <-chan L, right <-chan R, keyL func(L) K, keyR func(R) K, window time.Duration, opts ...Option) <-chan Joined[K, L, R] {
+	o := newOptions(opts)
+	out := make(chan Joined[K, L, R])
+	go func() {
+		defer close(out)
+		lefts := map[K][]*pending[L]{}
+		rights := map[K][]*pending[R]{}
+		var lOrder []K
+		var rOrder []K
+
+		emit := func(j Joined[K, L, R]) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case out <- j:
+				return true
+			}
+		}
+		// expire 移除过期的元素，force为true时移除全部(两边都关闭了)
+		expire := func(force bool) bool {
+			now := o.clock.Now()
+			ok := true
+			lOrder = expireSide(lefts, lOrder, now, window, o.maxBuffered, force, func(k K, p *pending[L]) {
+				if ok && !p.matched && o.unmatched == EmitUnmatched {
+					ok = emit(Joined[K, L, R]{Key: k, Left: p.value, HasLeft: true})
+				}
+			})
+			rOrder = expireSide(rights, rOrder, now, window, o.maxBuffered, force, func(k K, p *pending[R]) {
+				if ok && !p.matched && o.unmatched == EmitUnmatched {
+					ok = emit(Joined[K, L, R]{Key: k, Right: p.value, HasRight: true})
+				}
+			})
+			return ok
+		}
+
+		tick := window / 2
+		if tick <= 0 {
+			tick = time.Millisecond
+		}
+		ticker := o.clock.NewTicker(tick)
+		defer ticker.Stop()
+
+		for left != nil || right != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			case v, ok := <-left:
+				if !ok {
+					left = nil
+					continue
+				}
+				// 先移除过期的元素，ticker的清理可能还没轮到，不能和它们匹配
+				if !expire(false) {
+					return
+				}
+				k := keyL(v)
+				p := &pending[L]{value: v, at: o.clock.Now()}
+				for _, r := range rights[k] {
+					p.matched, r.matched = true, true
+					if !emit(Joined[K, L, R]{Key: k, Left: v, Right: r.value, HasLeft: true, HasRight: true}) {
+						return
+					}
+				}
+				lefts[k] = append(lefts[k], p)
+				lOrder = append(lOrder, k)
+			case v, ok := <-right:
+				if !ok {
+					right = nil
+					continue
+				}
+				if !expire(false) {
+					return
+				}
+				k := keyR(v)
+				p := &pending[R]{value: v, at: o.clock.Now()}
+				for _, l := range lefts[k] {
+					p.matched, l.matched = true, true
+					if !emit(Joined[K, L, R]{Key: k, Left: l.value, Right: v, HasLeft: true, HasRight: true}) {
+						return
+					}
+				}
+				rights[k] = append(rights[k], p)
+				rOrder = append(rOrder, k)
+			}
+			if !expire(false) {
+				return
+			}
+		}
+		expire(true)
+	}()
+	return out
+}
+
+// expireSide 按到达顺序移除一边过期或者超出缓冲的元素。order记录每个元素的key，和到达顺序一致
+func expireSide[K comparable, T any](buf map[K][]*pending[T], order []K, now time.Time, window time.Duration, max int, force bool, onExpire func(K, *pending[T])) []K {
+	for len(order) > 0 {
+		k := order[0]
+		p := buf[k][0]
+		if !force && now.Sub(p.at) <= window && len(order) <= max {
+			break
+		}
+		onExpire(k, p)
+		order = order[1:]
+		if len(buf[k]) == 1 {
+			delete(buf, k)
+		} else {
+			buf[k] = buf[k][1:]
+		}
+	}
+	return order
+}
@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func ints(values ...int) <-chan int {
+	ch := make(chan int, len(values))
+	for _, v := range values {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+// TestZipCase 逐个配对，短的一边结束时结束
+func TestZipCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := collect(Zip(ctx, ints(1, 2, 3), buffered("a", "b")))
+	assert.Equal(t, []Pair[int, string]{{1, "a"}, {2, "b"}}, got)
+}
+
+// TestCombineLatestCase 任意一边更新时和另一边最新的值组合
+func TestCombineLatestCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := make(chan int)
+	b := make(chan string)
+	out := CombineLatest(ctx, a, b)
+	a <- 1
+	a <- 2
+	b <- "x"
+	assert.Equal(t, Pair[int, string]{2, "x"}, <-out)
+	a <- 3
+	assert.Equal(t, Pair[int, string]{3, "x"}, <-out)
+	close(a)
+	b <- "y"
+	assert.Equal(t, Pair[int, string]{3, "y"}, <-out)
+	close(b)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+type order struct {
+	id    string
+	total int
+}
+
+type payment struct {
+	orderID string
+	amount  int
+}
+
+// TestJoinCase 窗口内相同key的订单和支付配对，过期没匹配上的单独输出
+func TestJoinCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := clock.NewFake(time.Now())
+	orders := make(chan order)
+	payments := make(chan payment)
+	out := Join(ctx, orders, payments,
+		func(o order) string { return o.id },
+		func(p payment) string { return p.orderID },
+		time.Minute, WithClock(fake), WithUnmatched(EmitUnmatched))
+
+	orders <- order{id: "o1", total: 10}
+	payments <- payment{orderID: "o1", amount: 10}
+	j := <-out
+	assert.True(t, j.HasLeft && j.HasRight)
+	assert.Equal(t, "o1", j.Key)
+	assert.Equal(t, 10, j.Right.amount)
+
+	payments <- payment{orderID: "o2", amount: 5}
+	orders <- order{id: "o3", total: 7}
+	fake.Advance(2 * time.Minute)
+	j = <-out
+	assert.Equal(t, Joined[string, order, payment]{Key: "o3", Left: order{id: "o3", total: 7}, HasLeft: true}, j)
+	j = <-out
+	assert.Equal(t, Joined[string, order, payment]{Key: "o2", Right: payment{orderID: "o2", amount: 5}, HasRight: true}, j)
+
+	close(orders)
+	close(payments)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+// TestJoinMaxBufferedCase 缓冲满了之后最旧的元素被移除，不会再参与匹配
+func TestJoinMaxBufferedCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	left := make(chan int)
+	right := make(chan int)
+	out := Join(ctx, left, right, func(i int) int { return i }, func(i int) int { return i }, time.Hour, WithMaxBuffered(2))
+	go func() {
+		defer close(left)
+		defer close(right)
+		left <- 1
+		left <- 2
+		left <- 3
+		right <- 1
+		right <- 3
+	}()
+	var keys []int
+	for j := range out {
+		keys = append(keys, j.Key)
+	}
+	assert.Equal(t, []int{3}, keys)
+}
+
+// TestJoinWindowCase 超过window的元素即使ticker还没来得及清理，也不会参与匹配
+func TestJoinWindowCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := clock.NewFake(time.Now())
+	left := make(chan int)
+	right := make(chan int)
+	out := Join(ctx, left, right, func(i int) int { return i }, func(i int) int { return i }, time.Minute, WithClock(fake))
+	keys := make(chan []int)
+	go func() {
+		var got []int
+		for j := range out {
+			got = append(got, j.Key)
+		}
+		keys <- got
+	}()
+
+	left <- 1
+	// 60秒时的tick清理不掉left 1，下一次tick在90秒，75秒时只能靠匹配之前的检查
+	fake.Advance(time.Minute)
+	left <- 3
+	fake.Advance(15 * time.Second)
+	right <- 1
+	right <- 2
+	left <- 2
+	close(left)
+	close(right)
+	assert.Equal(t, []int{2}, <-keys)
+}
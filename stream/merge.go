@@ -0,0 +1,59 @@
+package stream
+
+import (
+	"context"
+
+	"concurrenceWay/channel"
+	"concurrenceWay/internal/chans"
+)
+
+// PriorityMerge 按优先级合并，数值越大越优先。每次没被选中时有效优先级加一(老化)，所以低优先级的输入只会被推迟，不会被饿死
+func PriorityMerge[T any](ctx context.Context, inputs []<-chan T, priorities []int) <-chan T {
+	sel := channel.NewSelector[T](channel.Priority, channel.Aging())
+	addInputs(sel, inputs, priorities, channel.WithPriority)
+	return merge(ctx, sel)
+}
+
+// StrictPriorityMerge 严格优先级合并，高优先级的输入有数据时，低优先级的永远不会被选中，适合控制面消息必须先于数据面的场景。
+// starvationLimit大于0时开启饿死保护：一个输入连续starvationLimit次没被选中之后，它有数据时强制选中它一次
+func StrictPriorityMerge[T any](ctx context.Context, inputs []<-chan T, priorities []int, starvationLimit int) <-chan T {
+	sel := channel.NewSelector[T](channel.Priority, channel.StarvationLimit(starvationLimit))
+	addInputs(sel, inputs, priorities, channel.WithPriority)
+	return merge(ctx, sel)
+}
+
+// WeightedMerge 按权重比例合并，所有输入都有数据时，每路被选中的次数和权重成正比(平滑加权轮询)
+func WeightedMerge[T any](ctx context.Context, inputs []<-chan T, weights []int) <-chan T {
+	sel := channel.NewSelector[T](channel.Weighted)
+	addInputs(sel, inputs, weights, channel.WithWeight)
+	return merge(ctx, sel)
+}
+
+// addInputs 把inputs加入sel，第i路输入的优先级或者权重是values[i]，values不够长时用默认值
+func addInputs[T any](sel *channel.Selector[T], inputs []<-chan T, values []int, option func(int) channel.CaseOption) {
+	for i, in := range inputs {
+		if i < len(values) {
+			sel.Add(in, option(values[i]))
+		} else {
+			sel.Add(in)
+		}
+	}
+}
+
+// merge 由sel决定下一个读哪路输入，关闭的输入被sel自动移除，所有输入都关闭后关闭输出
+func merge[T any](ctx context.Context, sel *channel.Selector[T]) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for sel.Len() > 0 {
+			_, v, ok, err := sel.Select(ctx)
+			if err != nil {
+				return
+			}
+			if ok && !chans.Send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
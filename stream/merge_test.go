@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buffered(values ...string) <-chan string {
+	ch := make(chan string, len(values))
+	for _, v := range values {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+func collect[T any](ch <-chan T) []T {
+	var got []T
+	for v := range ch {
+		got = append(got, v)
+	}
+	return got
+}
+
+// TestStrictPriorityMergeCase 控制面消息总是先于数据面
+func TestStrictPriorityMergeCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bulk := buffered("b", "b", "b")
+	control := buffered("c", "c", "c")
+	got := collect(StrictPriorityMerge(ctx, []<-chan string{bulk, control}, []int{0, 10}, 0))
+	assert.Equal(t, []string{"c", "c", "c", "b", "b", "b"}, got)
+}
+
+// TestStrictPriorityStarvationCase 低优先级连续被跳过两次之后强制选中一次
+func TestStrictPriorityStarvationCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bulk := buffered("b", "b", "b")
+	control := buffered("c", "c", "c")
+	got := collect(StrictPriorityMerge(ctx, []<-chan string{bulk, control}, []int{0, 10}, 2))
+	assert.Equal(t, []string{"c", "c", "b", "c", "b", "b"}, got)
+}
+
+// TestPriorityMergeCase 老化：每跳过一次有效优先级加一，低优先级的只会被推迟
+func TestPriorityMergeCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	low := buffered("l", "l", "l", "l")
+	high := buffered("h", "h", "h", "h", "h", "h")
+	got := collect(PriorityMerge(ctx, []<-chan string{low, high}, []int{0, 1}))
+	assert.Equal(t, []string{"h", "h", "l", "h", "h", "l", "h", "h", "l", "l"}, got)
+}
+
+// TestWeightedMergeCase 都有数据时按3:1的比例选择
+func TestWeightedMergeCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	heavy := buffered("h", "h", "h", "h", "h", "h", "h", "h")
+	light := buffered("l", "l", "l", "l", "l", "l", "l", "l")
+	got := collect(WeightedMerge(ctx, []<-chan string{heavy, light}, []int{3, 1}))
+	counts := map[string]int{}
+	for _, v := range got[:8] {
+		counts[v]++
+	}
+	assert.Equal(t, map[string]int{"h": 6, "l": 2}, counts)
+	assert.Len(t, got, 16)
+}
+
+// TestMergeBlockingCase 输入还没有数据时阻塞等待，所有输入关闭后输出关闭
+func TestMergeBlockingCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := make(chan int)
+	b := make(chan int)
+	go func() {
+		defer close(a)
+		defer close(b)
+		for i := 0; i < 3; i++ {
+			a <- i
+			b <- i * 10
+		}
+	}()
+	got := collect(WeightedMerge(ctx, []<-chan int{a, b}, nil))
+	sort.Ints(got)
+	assert.Equal(t, []int{0, 0, 1, 2, 10, 20}, got)
+}
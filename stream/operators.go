@@ -0,0 +1,334 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"concurrenceWay/clock"
+	"concurrenceWay/internal/chans"
+	"concurrenceWay/pipeline"
+)
+
+// ErrTimeout Timeout在规定时间内没有等到下一个元素
+var ErrTimeout = errors.New("stream: timeout")
+
+// timer 懒创建、可以安全重置的定时器，没有启动时C()返回nil，放在select里永远不会被选中
+type timer struct {
+	clock clock.Clock
+	t     clock.Timer
+	armed bool
+}
+
+func (t *timer) C() <-chan time.Time {
+	if !t.armed {
+		return nil
+	}
+	return t.t.C()
+}
+
+// reset 重新开始计时，先把可能残留在通道里的旧值取掉，否则会马上触发
+func (t *timer) reset(d time.Duration) {
+	if t.t == nil {
+		t.t = t.clock.NewTimer(d)
+	} else {
+		t.disarm()
+		t.t.Reset(d)
+	}
+	t.armed = true
+}
+
+func (t *timer) disarm() {
+	if t.t != nil && !t.t.Stop() {
+		select {
+		case <-t.t.C():
+		default:
+		}
+	}
+	t.armed = false
+}
+
+// fired 在select中收到C()的值之后调用
+func (t *timer) fired() { t.armed = false }
+
+// Debounce 防抖，输入安静了d之后才输出最后一个元素，适合配置热加载这种短时间内连续变更只关心最终结果的场景。输入关闭时输出还没发出去的元素
+func Debounce[T any](ctx context.Context, in <-chan T, d time.Duration, opts ...Option) <-chan T {
+	o := newOptions(opts)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		t := &timer{clock: o.clock}
+		defer t.disarm()
+		var latest T
+		pending := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					if pending {
+						chans.Send(ctx, out, latest)
+					}
+					return
+				}
+				latest, pending = v, true
+				t.reset(d)
+			case <-t.C():
+				t.fired()
+				pending = false
+				if !chans.Send(ctx, out, latest) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ThrottleFirst 节流，输出一个元素之后的d时间内，其余元素都被丢弃
+func ThrottleFirst[T any](ctx context.Context, in <-chan T, d time.Duration, opts ...Option) <-chan T {
+	o := newOptions(opts)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		t := &timer{clock: o.clock}
+		defer t.disarm()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C():
+				t.fired()
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				// 窗口可能已经到期，只是还没被select选中
+				select {
+				case <-t.C():
+					t.fired()
+				default:
+				}
+				if t.armed {
+					continue
+				}
+				t.reset(d)
+				if !chans.Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// ThrottleLast 节流，第一个元素到达时开始一个d长的窗口，窗口结束时输出窗口内的最后一个元素。输入关闭时输出还没发出去的元素
+func ThrottleLast[T any](ctx context.Context, in <-chan T, d time.Duration, opts ...Option) <-chan T {
+	o := newOptions(opts)
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		t := &timer{clock: o.clock}
+		defer t.disarm()
+		var latest T
+		pending := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					if pending {
+						chans.Send(ctx, out, latest)
+					}
+					return
+				}
+				latest = v
+				if !pending {
+					pending = true
+					t.reset(d)
+				}
+			case <-t.C():
+				t.fired()
+				pending = false
+				if !chans.Send(ctx, out, latest) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Sample 采样，每次tick时输出上一次tick之后收到的最新元素，期间没有新元素就不输出
+func Sample[T any](ctx context.Context, in <-chan T, tick <-chan time.Time) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var latest T
+		fresh := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				latest, fresh = v, true
+			case <-tick:
+				if !fresh {
+					continue
+				}
+				fresh = false
+				if !chans.Send(ctx, out, latest) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// DistinctUntilChanged 和上一个输出的元素相等(==)时丢弃
+func DistinctUntilChanged[T comparable](ctx context.Context, in <-chan T) <-chan T {
+	return DistinctUntilChangedFunc(ctx, in, func(a, b T) bool { return a == b })
+}
+
+// DistinctUntilChangedFunc 和上一个输出的元素eq时丢弃，用于切片、map这类不能用==比较的类型，或者自定义相等的规则
+func DistinctUntilChangedFunc[T any](ctx context.Context, in <-chan T, eq func(a, b T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var last T
+		first := true
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !first && eq(last, v) {
+					continue
+				}
+				first, last = false, v
+				if !chans.Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Skip 丢弃前n个元素
+func Skip[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if n > 0 {
+					n--
+					continue
+				}
+				if !chans.Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// TakeWhile 一直输出，直到第一个不满足pred的元素出现。和take一样，提前结束后上游需要通过ctx取消
+func TakeWhile[T any](ctx context.Context, in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok || !pred(v) {
+					return
+				}
+				if !chans.Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// TakeUntil 一直输出，直到signal有值或者被关闭
+func TakeUntil[T, S any](ctx context.Context, in <-chan T, signal <-chan S) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signal:
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-signal:
+					return
+				case out <- v:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Timeout 开始之后或者上一个元素之后d时间内没有新元素，输出一个ErrTimeout的结果并结束。等待下游消费的时间不计入
+func Timeout[T any](ctx context.Context, in <-chan T, d time.Duration, opts ...Option) <-chan pipeline.Result[T] {
+	o := newOptions(opts)
+	out := make(chan pipeline.Result[T])
+	go func() {
+		defer close(out)
+		t := &timer{clock: o.clock}
+		defer t.disarm()
+		t.reset(d)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				t.disarm()
+				if !chans.Send(ctx, out, pipeline.Result[T]{Value: v}) {
+					return
+				}
+				t.reset(d)
+			case <-t.C():
+				t.fired()
+				chans.Send(ctx, out, pipeline.Result[T]{Err: fmt.Errorf("%w: no element within %v", ErrTimeout, d)})
+				return
+			}
+		}
+	}()
+	return out
+}
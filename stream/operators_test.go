@@ -0,0 +1,188 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"concurrenceWay/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+// advanceUntil 不断推进时间直到out有输出。防抖的定时器在收到元素之后才重置，和Advance之间没有先后保证
+func advanceUntil[T any](fake *clock.Fake, d time.Duration, out <-chan T) T {
+	for {
+		fake.Advance(d)
+		select {
+		case v := <-out:
+			return v
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// TestDebounceCase 连续的变更只输出最后一个，输入关闭时输出还没发出去的元素
+func TestDebounceCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := clock.NewFake(time.Now())
+	in := make(chan int)
+	out := Debounce(ctx, in, time.Second, WithClock(fake))
+	in <- 1
+	in <- 2
+	in <- 3
+	assert.Equal(t, 3, advanceUntil(fake, time.Second, out))
+	in <- 4
+	close(in)
+	assert.Equal(t, []int{4}, collect(out))
+}
+
+// TestThrottleFirstCase 窗口内只有第一个元素被输出
+func TestThrottleFirstCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := clock.NewFake(time.Now())
+	in := make(chan int)
+	out := ThrottleFirst(ctx, in, time.Second, WithClock(fake))
+	go func() {
+		defer close(in)
+		in <- 1
+		in <- 2
+		in <- 3
+		fake.Advance(time.Second)
+		in <- 4
+		in <- 5
+	}()
+	got := collect(out)
+	// 发出3之后ThrottleFirst可能在Advance之前或者之后才处理它，窗口到期后的第一个元素是3或者4
+	assert.Len(t, got, 2)
+	assert.Equal(t, 1, got[0])
+	assert.Contains(t, []int{3, 4}, got[1])
+}
+
+// TestThrottleLastCase 窗口结束时输出窗口内的最后一个元素
+func TestThrottleLastCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := clock.NewFake(time.Now())
+	in := make(chan int)
+	out := ThrottleLast(ctx, in, time.Second, WithClock(fake))
+	in <- 1
+	in <- 2
+	in <- 3
+	fake.Advance(time.Second)
+	assert.Equal(t, 3, <-out)
+	in <- 4
+	close(in)
+	assert.Equal(t, []int{4}, collect(out))
+}
+
+// TestSampleCase 每次tick输出最新的元素，两次tick之间没有新元素时不输出
+func TestSampleCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan int)
+	tick := make(chan time.Time)
+	out := Sample(ctx, in, tick)
+	in <- 1
+	in <- 2
+	tick <- time.Now()
+	assert.Equal(t, 2, <-out)
+	tick <- time.Now()
+	in <- 3
+	tick <- time.Now()
+	assert.Equal(t, 3, <-out)
+	close(in)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+// TestDistinctUntilChangedCase 只丢弃和上一个相同的元素，不是全局去重
+func TestDistinctUntilChangedCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := collect(DistinctUntilChanged(ctx, buffered("a", "a", "b", "b", "a")))
+	assert.Equal(t, []string{"a", "b", "a"}, got)
+
+	got = collect(DistinctUntilChangedFunc(ctx, buffered("a", "A", "b"), strings.EqualFold))
+	assert.Equal(t, []string{"a", "b"}, got)
+
+	// 切片不能用==比较
+	in := make(chan []byte, 3)
+	in <- []byte("x")
+	in <- []byte("x")
+	in <- []byte("y")
+	close(in)
+	bs := collect(DistinctUntilChangedFunc(ctx, in, bytes.Equal))
+	assert.Equal(t, [][]byte{[]byte("x"), []byte("y")}, bs)
+}
+
+// TestSkipTakeWhileCase 组合使用Skip和TakeWhile
+func TestSkipTakeWhileCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := collect(TakeWhile(ctx, Skip(ctx, ints(1, 2, 3, 4, 5, 1), 2), func(i int) bool { return i < 5 }))
+	assert.Equal(t, []int{3, 4}, got)
+}
+
+// TestTakeUntilCase signal关闭之后不再输出
+func TestTakeUntilCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan int)
+	signal := make(chan struct{})
+	out := TakeUntil(ctx, in, signal)
+	in <- 1
+	assert.Equal(t, 1, <-out)
+	close(signal)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+// TestTimeoutCase 元素之间的间隔超过d时输出ErrTimeout并结束
+func TestTimeoutCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := clock.NewFake(time.Now())
+	in := make(chan int)
+	out := Timeout(ctx, in, time.Second, WithClock(fake))
+	fake.BlockUntil(1)
+	fake.Advance(500 * time.Millisecond)
+	in <- 1
+	r := <-out
+	assert.NoError(t, r.Err)
+	assert.Equal(t, 1, r.Value)
+
+	// 收到元素之后重新计时
+	fake.BlockUntil(1)
+	fake.Advance(900 * time.Millisecond)
+	in <- 2
+	assert.Equal(t, 2, (<-out).Value)
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	r = <-out
+	assert.True(t, errors.Is(r.Err, ErrTimeout))
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+// TestOperatorsCancelCase ctx取消之后输出关闭，不会泄漏goroutine
+func TestOperatorsCancelCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	outs := []<-chan int{
+		Debounce(ctx, in, time.Second),
+		ThrottleFirst(ctx, in, time.Second),
+		ThrottleLast(ctx, in, time.Second),
+		Skip(ctx, in, 1),
+	}
+	cancel()
+	for _, out := range outs {
+		_, ok := <-out
+		assert.False(t, ok)
+	}
+}
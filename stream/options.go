@@ -0,0 +1,38 @@
+package stream
+
+import (
+	"concurrenceWay/clock"
+)
+
+// Option 和时间、缓冲相关的操作符的可选项，每个操作符只使用和自己相关的选项，其余的忽略
+type Option func(*options)
+
+type options struct {
+	clock       clock.Clock
+	unmatched   Unmatched
+	maxBuffered int
+}
+
+// WithClock 指定时钟，测试时传入clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+// WithUnmatched Join中没有匹配上的元素怎么处理，默认丢弃
+func WithUnmatched(u Unmatched) Option {
+	return func(o *options) { o.unmatched = u }
+}
+
+// WithMaxBuffered Join每一边最多缓冲的元素数，超过时最旧的元素被当作过期处理，默认1024
+func WithMaxBuffered(n int) Option {
+	return func(o *options) { o.maxBuffered = n }
+}
+
+func newOptions(opts []Option) options {
+	o := options{maxBuffered: 1024}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.clock = clock.OrReal(o.clock)
+	return o
+}
@@ -0,0 +1,125 @@
+package stream
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	"concurrenceWay/pipeline"
+)
+
+// virtualNodes 每个分区在哈希环上的虚拟节点数，越多分布越均匀
+const virtualNodes = 64
+
+// Ring 一致性哈希环，分区数变化时只有少量key会换到别的分区
+type Ring struct {
+	points     []uint64
+	partitions []int
+}
+
+// NewRing 创建有n个分区的哈希环
+func NewRing(n int) *Ring {
+	r := &Ring{}
+	type point struct {
+		hash      uint64
+		partition int
+	}
+	points := make([]point, 0, n*virtualNodes)
+	for p := 0; p < n; p++ {
+		for v := 0; v < virtualNodes; v++ {
+			points = append(points, point{hash: hashKey(strconv.Itoa(p) + "#" + strconv.Itoa(v)), partition: p})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].hash < points[j].hash })
+	for _, pt := range points {
+		r.points = append(r.points, pt.hash)
+		r.partitions = append(r.partitions, pt.partition)
+	}
+	return r
+}
+
+// Locate 返回key所在的分区，顺时针找到的第一个虚拟节点所属的分区
+func (r *Ring) Locate(key string) int {
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.partitions[i]
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	// fnv对相近的字符串区分度不够，再混合一次
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+// Partition 按key分区的扇出。相同key的元素总是进入同一个输出，并且保持原来的顺序。
+// 某一个分区的消费者慢下来时会阻塞所有分区，需要的话在输出后面接buffer
+func Partition[T any](ctx context.Context, in <-chan T, n int, keyFn func(T) string) []<-chan T {
+	if n < 1 {
+		n = 1
+	}
+	ring := NewRing(n)
+	outs := make([]chan T, n)
+	views := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T)
+		views[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case outs[ring.Locate(keyFn(v))] <- v:
+				}
+			}
+		}
+	}()
+	return views
+}
+
+// KeyedParallelMap 不同key的元素并行处理，相同key的元素在同一个worker里按顺序处理，所以输出中每个key的顺序和输入一致
+func KeyedParallelMap[T, R any](ctx context.Context, in <-chan T, workers int, keyFn func(T) string, stage pipeline.Stage[T, R]) <-chan pipeline.Result[R] {
+	partitions := Partition(ctx, in, workers, keyFn)
+	out := make(chan pipeline.Result[R])
+	var wg sync.WaitGroup
+	wg.Add(len(partitions))
+	for _, p := range partitions {
+		go func(p <-chan T) {
+			defer wg.Done()
+			for r := range pipeline.Map(ctx, p, stage) {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- r:
+				}
+			}
+		}(p)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
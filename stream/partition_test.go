@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	user string
+	seq  int
+}
+
+func events(users []string, perUser int) <-chan event {
+	out := make(chan event)
+	go func() {
+		defer close(out)
+		for seq := 0; seq < perUser; seq++ {
+			for _, u := range users {
+				out <- event{user: u, seq: seq}
+			}
+		}
+	}()
+	return out
+}
+
+// TestRingCase 分区数从4变成5时，只有大约1/5的key会换分区
+func TestRingCase(t *testing.T) {
+	r4, r5 := NewRing(4), NewRing(5)
+	moved := 0
+	counts := make([]int, 4)
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		p := r4.Locate(key)
+		counts[p]++
+		if p != r5.Locate(key) {
+			moved++
+		}
+	}
+	fmt.Println("distribution:", counts, "moved:", moved)
+	assert.InDelta(t, 2000, moved, 600)
+	for _, c := range counts {
+		assert.InDelta(t, 2500, c, 1000)
+	}
+}
+
+// TestPartitionCase 相同key总是进入同一个输出，并且保持顺序
+func TestPartitionCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	users := []string{"alice", "bob", "carol", "dave", "erin", "frank"}
+	outs := Partition(ctx, events(users, 20), 3, func(e event) string { return e.user })
+
+	var mu sync.Mutex
+	owner := map[string]int{}
+	var wg sync.WaitGroup
+	for i, out := range outs {
+		wg.Add(1)
+		go func(i int, out <-chan event) {
+			defer wg.Done()
+			last := map[string]int{}
+			for e := range out {
+				mu.Lock()
+				if p, ok := owner[e.user]; ok {
+					assert.Equal(t, p, i, "user %s in two partitions", e.user)
+				}
+				owner[e.user] = i
+				mu.Unlock()
+				if prev, ok := last[e.user]; ok {
+					assert.Equal(t, prev+1, e.seq)
+				}
+				last[e.user] = e.seq
+			}
+		}(i, out)
+	}
+	wg.Wait()
+	assert.Len(t, owner, len(users))
+}
+
+// TestKeyedParallelMapCase 不同key并行处理，每个key的输出顺序和输入一致
+func TestKeyedParallelMapCase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	users := []string{"alice", "bob", "carol", "dave"}
+	slow := func(ctx context.Context, e event) (event, error) {
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+		return e, nil
+	}
+	last := map[string]int{}
+	total := 0
+	for r := range KeyedParallelMap(ctx, events(users, 25), 4, func(e event) string { return e.user }, slow) {
+		assert.NoError(t, r.Err)
+		if prev, ok := last[r.Value.user]; ok {
+			assert.Equal(t, prev+1, r.Value.seq)
+		}
+		last[r.Value.user] = r.Value.seq
+		total++
+	}
+	assert.Equal(t, 100, total)
+}
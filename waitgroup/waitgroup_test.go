@@ -1,3 +1,6 @@
+// 这个文件演示go1.22之前循环变量被所有迭代共享的问题，go.mod升级之后用构建约束把它保持在go1.21的语义
+//go:build go1.21
+
 package waitgroup
 
 import (